@@ -0,0 +1,107 @@
+// Package apperrors defines the errors processors return to describe why a
+// request failed. Each carries a Kind, which decides the HTTP status, and a
+// stable machine-readable Code, which clients can rely on while the
+// human-readable Message changes.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	default:
+		return "internal"
+	}
+}
+
+// internalMessage is all a client learns about an internal error.
+const internalMessage = "что-то пошло не так.Повторите попытку чуть позже"
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Err is the underlying cause. It is logged but never sent to clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil && e.Kind == KindInternal {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same code, so that errors
+// declared as package variables can be matched with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func newError(kind Kind, code, format string, args ...any) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+func NotFound(code, format string, args ...any) *Error {
+	return newError(KindNotFound, code, format, args...)
+}
+func Conflict(code, format string, args ...any) *Error {
+	return newError(KindConflict, code, format, args...)
+}
+func Validation(code, format string, args ...any) *Error {
+	return newError(KindValidation, code, format, args...)
+}
+func Unauthorized(code, format string, args ...any) *Error {
+	return newError(KindUnauthorized, code, format, args...)
+}
+func Forbidden(code, format string, args ...any) *Error {
+	return newError(KindForbidden, code, format, args...)
+}
+
+// Internal hides err behind a generic message.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal", Message: internalMessage, Err: err}
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// From returns the *Error in err's chain, treating any other error as
+// internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"og-style/types"
+	"og-style/utils"
+	"os"
+)
+
+//go:embed seed.json
+var defaultSeed []byte
+
+type seedData struct {
+	Brands   []string      `json:"brands"`
+	Products []seedProduct `json:"products"`
+}
+
+// seedProduct refers to its brand by name, which is resolved to the ID the
+// brand got in this database.
+type seedProduct struct {
+	types.CreateProduct
+	BrandName string                `json:"brand"`
+	Variants  []types.CreateVariant `json:"variants"`
+}
+
+// seed creates the brands and products from the seed file (the embedded
+// demo catalog by default). It can be run repeatedly: existing brands are
+// reused and products whose first variant SKU exists are skipped.
+func (a *app) seed(ctx context.Context, args []string) error {
+	var file string
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.StringVar(&file, "file", "", "JSON seed file; defaults to the built-in demo catalog")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content := defaultSeed
+	if file != "" {
+		var err error
+		if content, err = os.ReadFile(file); err != nil {
+			return err
+		}
+	}
+
+	var data seedData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("seed file: %w", err)
+	}
+
+	brandIds := map[string]int{}
+	for _, name := range data.Brands {
+		brandId, err := a.brandStorage.Create(ctx, name)
+		if err != nil {
+			return err
+		}
+		brandIds[name] = brandId
+	}
+	fmt.Printf("brands: %d\n", len(brandIds))
+
+	created, skipped := 0, 0
+	for _, product := range data.Products {
+		brandId, ok := brandIds[product.BrandName]
+		if !ok {
+			return fmt.Errorf("product %q: unknown brand %q", product.Name, product.BrandName)
+		}
+		product.Brand = brandId
+
+		if len(product.Variants) == 0 {
+			return fmt.Errorf("product %q: no variants", product.Name)
+		}
+
+		if err := checkSeed(product.Name, product.CreateProduct); err != nil {
+			return err
+		}
+		for _, variant := range product.Variants {
+			if err := checkSeed(product.Name+" "+variant.SKU, variant); err != nil {
+				return err
+			}
+		}
+
+		// A product and its variants are created together, so a failed run
+		// never leaves a product behind that the SKU check would then skip.
+		exists := false
+		err := a.tx.WithinTx(ctx, func(ctx context.Context) error {
+			existing, err := a.variantStorage.GetBySKU(ctx, product.Variants[0].SKU)
+			if err != nil {
+				return err
+			}
+			if exists = existing.ID != 0; exists {
+				return nil
+			}
+
+			productId, err := a.productStorage.Create(ctx, &product.CreateProduct)
+			if err != nil {
+				return err
+			}
+
+			for _, variant := range product.Variants {
+				if _, err := a.variantStorage.Create(ctx, productId, &variant); err != nil {
+					return fmt.Errorf("product %q variant %s: %w", product.Name, variant.SKU, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			skipped++
+		} else {
+			created++
+		}
+	}
+
+	fmt.Printf("products: %d created, %d already present\n", created, skipped)
+	return nil
+}
+func (a *app) reindex(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: ogctl reindex")
+	}
+
+	if err := a.productStorage.Reindex(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("rebuilt the catalog indexes")
+	return nil
+}
+func checkSeed(name string, data any) error {
+	errs := utils.ValidateStruct(data, "en")
+	if errs == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s: invalid %s: %s", name, errs[0].Field, errs[0].Message)
+}
@@ -0,0 +1,131 @@
+// Command ogctl runs operational tasks against the og-style database:
+// managing users, roles, passwords and sessions, migrating the schema,
+// seeding the catalog and rebuilding its indexes.
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/config"
+	"og-style/db"
+	"og-style/internal/migratecmd"
+	"og-style/processors"
+	"og-style/services"
+	"os"
+	"os/signal"
+)
+
+const usage = `usage: ogctl <command> [arguments]
+
+commands:
+  user create -email EMAIL -password PASSWORD [-locale ru|en] [-role ROLE]... [-verified]
+  role grant EMAIL ROLE
+  role revoke EMAIL ROLE
+  password reset [-password PASSWORD] EMAIL
+  sessions revoke EMAIL
+  ` + migratecmd.Usage + `
+  seed [-file FILE]
+  reindex
+
+The configuration is read like the server's: config file, .env and environment.`
+
+type app struct {
+	cfg *config.Config
+	db  *pgxpool.Pool
+
+	userStorage    db.UserStorage
+	cartStorage    db.CartStorage
+	tokenStorage   db.TokenStorage
+	productStorage db.ProductStorage
+	variantStorage db.VariantStorage
+	brandStorage   db.BrandStorage
+	tx             db.TxManager
+	authProcessor  *processors.AuthPgProcessor
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "ogctl:", err)
+		os.Exit(1)
+	}
+}
+func run(command string, args []string) error {
+	cfg, err := config.Load("", "Database")
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	a := newApp(cfg, pool)
+
+	switch command {
+	case "user":
+		return a.user(ctx, args)
+	case "role":
+		return a.role(ctx, args)
+	case "password":
+		return a.password(ctx, args)
+	case "sessions":
+		return a.sessions(ctx, args)
+	case "migrate":
+		return migratecmd.Run(ctx, &db.Migrator{DB: pool}, args, os.Stdout)
+	case "seed":
+		return a.seed(ctx, args)
+	case "reindex":
+		return a.reindex(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+func newApp(cfg *config.Config, pool *pgxpool.Pool) *app {
+	var (
+		userStorage    = db.UserPgStorage{DB: pool}
+		cartStorage    = db.CartPgStorage{DB: pool}
+		tokenStorage   = db.TokenPgStorage{DB: pool}
+		productStorage = db.ProductPgStorage{DB: pool, PageSize: cfg.Products.PageSize}
+		variantStorage = db.VariantPgStorage{DB: pool}
+		brandStorage   = db.BrandPgStorage{DB: pool}
+		txManager      = db.PgTxManager{DB: pool, MaxRetries: cfg.Database.TxRetries}
+	)
+
+	return &app{
+		cfg:            cfg,
+		db:             pool,
+		userStorage:    &userStorage,
+		cartStorage:    &cartStorage,
+		tokenStorage:   &tokenStorage,
+		productStorage: &productStorage,
+		variantStorage: &variantStorage,
+		brandStorage:   &brandStorage,
+		tx:             &txManager,
+		authProcessor:  &processors.AuthPgProcessor{UserStorage: &userStorage, CartStorage: &cartStorage, TokenStorage: &tokenStorage, Tx: &txManager, Config: cfg.Auth},
+	}
+}
+
+// emails loads the email templates on first use, so that only commands which
+// send email need them on disk.
+func (a *app) emails() (services.EmailRenderer, error) {
+	if a.authProcessor.Emails == nil {
+		renderer, err := services.NewTemplateEmailRenderer(a.cfg.Mail.TemplatesDir, a.cfg.Mail.FrontendURL)
+		if err != nil {
+			return nil, err
+		}
+		a.authProcessor.Emails = renderer
+	}
+
+	return a.authProcessor.Emails, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"og-style/models"
+	"og-style/services"
+	"og-style/types"
+	"og-style/utils"
+	"strings"
+)
+
+var roles = []string{"user", "admin"}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func (a *app) user(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New("usage: ogctl user create -email EMAIL -password PASSWORD [-locale ru|en] [-role ROLE]... [-verified]")
+	}
+
+	var data types.CreateUser
+	var grant stringList
+	var verified bool
+
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	fs.StringVar(&data.Email, "email", "", "email address")
+	fs.StringVar(&data.Password, "password", "", "password, at least 8 characters")
+	fs.StringVar(&data.Locale, "locale", services.DefaultLocale, "locale of the user's emails")
+	fs.Var(&grant, "role", "role to grant in addition to user; can be repeated")
+	fs.BoolVar(&verified, "verified", false, "mark the email address as verified")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := validate(data); err != nil {
+		return err
+	}
+	for _, role := range grant {
+		if err := checkRole(role); err != nil {
+			return err
+		}
+	}
+
+	existing, err := a.userStorage.GetByEmail(ctx, data.Email)
+	if err != nil {
+		return err
+	}
+	if existing.ID != 0 {
+		return fmt.Errorf("user %s already exists", data.Email)
+	}
+
+	if data.Password, err = utils.HashPassword(data.Password); err != nil {
+		return err
+	}
+
+	var userId int
+	err = a.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if userId, err = a.userStorage.Create(ctx, &data); err != nil {
+			return err
+		}
+
+		if err := a.cartStorage.Create(ctx, userId); err != nil {
+			return err
+		}
+
+		for _, role := range grant {
+			if err := a.userStorage.GrantRole(ctx, userId, role); err != nil {
+				return err
+			}
+		}
+
+		if verified {
+			return a.userStorage.MarkVerified(ctx, userId)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created user %d (%s)\n", userId, data.Email)
+	return nil
+}
+func (a *app) role(ctx context.Context, args []string) error {
+	if len(args) != 3 || (args[0] != "grant" && args[0] != "revoke") {
+		return errors.New("usage: ogctl role grant|revoke EMAIL ROLE")
+	}
+
+	user, err := a.findUser(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	role := args[2]
+	if err := checkRole(role); err != nil {
+		return err
+	}
+
+	if args[0] == "grant" {
+		if err := a.userStorage.GrantRole(ctx, user.ID, role); err != nil {
+			return err
+		}
+		fmt.Printf("granted %s to %s\n", role, user.Email)
+		return nil
+	}
+
+	if role == "user" {
+		return errors.New("the user role cannot be revoked")
+	}
+
+	if err := a.userStorage.RevokeRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+	fmt.Printf("revoked %s from %s\n", role, user.Email)
+	return nil
+}
+
+// password sends the user a reset link, or with -password sets the password
+// directly and signs the user out everywhere.
+func (a *app) password(ctx context.Context, args []string) error {
+	const passwordUsage = "usage: ogctl password reset [-password PASSWORD] EMAIL"
+	if len(args) == 0 || args[0] != "reset" {
+		return errors.New(passwordUsage)
+	}
+
+	var password string
+	fs := flag.NewFlagSet("password reset", flag.ContinueOnError)
+	fs.StringVar(&password, "password", "", "new password; without it a reset link is emailed")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(passwordUsage)
+	}
+
+	user, err := a.findUser(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	emails, err := a.emails()
+	if err != nil {
+		return err
+	}
+
+	if password == "" {
+		if err := a.authProcessor.ForgotPassword(ctx, user.Email); err != nil {
+			return err
+		}
+		fmt.Printf("queued a password reset link for %s\n", user.Email)
+		return nil
+	}
+
+	if err := validate(types.CreateUser{Email: user.Email, Password: password}); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	mail, err := emails.Render(services.EmailPasswordChanged, user.Locale, user.Email, nil)
+	if err != nil {
+		return err
+	}
+
+	err = a.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := a.userStorage.UpdatePassword(ctx, user.ID, hashedPassword, mail); err != nil {
+			return err
+		}
+
+		return a.authProcessor.LogoutAll(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("updated the password of %s and revoked their sessions\n", user.Email)
+	return nil
+}
+func (a *app) sessions(ctx context.Context, args []string) error {
+	if len(args) != 2 || args[0] != "revoke" {
+		return errors.New("usage: ogctl sessions revoke EMAIL")
+	}
+
+	user, err := a.findUser(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := a.authProcessor.LogoutAll(ctx, user.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("revoked all sessions of %s\n", user.Email)
+	return nil
+}
+func (a *app) findUser(ctx context.Context, email string) (*models.User, error) {
+	user, err := a.userStorage.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ID == 0 {
+		return nil, fmt.Errorf("user %s does not exist", email)
+	}
+
+	return user, nil
+}
+func checkRole(role string) error {
+	for _, r := range roles {
+		if r == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(roles, ", "))
+}
+func validate(data types.CreateUser) error {
+	if errs := utils.ValidateStruct(data, "en"); errs != nil {
+		return errs
+	}
+	return nil
+}
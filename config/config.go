@@ -0,0 +1,134 @@
+package config
+
+import "time"
+
+// Config is the server configuration. Values are resolved in order: the
+// `default` tag, the optional YAML file, the .env file and finally the
+// process environment named by the `env` tag.
+type Config struct {
+	Env        string           `yaml:"env" env:"GO_ENV" default:"development" validate:"oneof=development production test"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Cloudinary CloudinaryConfig `yaml:"cloudinary"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Mail       MailConfig       `yaml:"mail"`
+	Products   ProductsConfig   `yaml:"products"`
+	Inventory  InventoryConfig  `yaml:"inventory"`
+	Payments   PaymentsConfig   `yaml:"payments"`
+	Health     HealthConfig     `yaml:"health"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Addr        string        `yaml:"addr" env:"HTTP_ADDR" default:":4000" validate:"required"`
+	ReadTimeout time.Duration `yaml:"readTimeout" env:"HTTP_READ_TIMEOUT" default:"4s" validate:"gt=0"`
+	IdleTimeout time.Duration `yaml:"idleTimeout" env:"HTTP_IDLE_TIMEOUT" default:"120s" validate:"gt=0"`
+	// AdminAddr serves /metrics; keep it off the public network.
+	AdminAddr string `yaml:"adminAddr" env:"HTTP_ADMIN_ADDR" default:"127.0.0.1:9090" validate:"required"`
+	// ShutdownDelay is how long the server keeps serving after reporting
+	// not-ready, giving load balancers time to stop routing to it.
+	ShutdownDelay time.Duration `yaml:"shutdownDelay" env:"HTTP_SHUTDOWN_DELAY" default:"0s" validate:"gte=0"`
+	// DrainTimeout bounds both waiting for in-flight requests and stopping
+	// the background jobs.
+	DrainTimeout time.Duration `yaml:"drainTimeout" env:"HTTP_DRAIN_TIMEOUT" default:"20s" validate:"gt=0"`
+	CORSOrigins  []string      `yaml:"corsOrigins" env:"CORS_ORIGINS" default:"[\"http://localhost:5173\"]" validate:"required,dive,url"`
+	CORSDebug    bool          `yaml:"corsDebug" env:"CORS_DEBUG"`
+	// TrustedProxies are the CIDRs of the reverse proxies allowed to set
+	// X-Forwarded-For; requests from anywhere else use the remote address.
+	TrustedProxies []string `yaml:"trustedProxies" env:"HTTP_TRUSTED_PROXIES" validate:"dive,cidr"`
+}
+
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DB_CONNECTION" validate:"required"`
+	// QueryTimeout bounds every storage call; QueryTimeouts overrides it per
+	// operation, keyed like "ProductStorage.GetAll".
+	QueryTimeout  time.Duration            `yaml:"queryTimeout" env:"DB_QUERY_TIMEOUT" default:"5s" validate:"gt=0"`
+	QueryTimeouts map[string]time.Duration `yaml:"queryTimeouts" validate:"dive,gt=0"`
+	// TxRetries is how many times a transaction aborted by a serialization
+	// failure or deadlock is run again.
+	TxRetries int `yaml:"txRetries" env:"DB_TX_RETRIES" default:"3" validate:"gte=0"`
+}
+
+type CloudinaryConfig struct {
+	URL string `yaml:"url" env:"CLOUDINARY_URL" validate:"required"`
+}
+
+type AuthConfig struct {
+	JWTSecret            string        `yaml:"jwtSecret" env:"JWT_SECRET" validate:"required"`
+	AccessTokenTTL       time.Duration `yaml:"accessTokenTTL" env:"ACCESS_TOKEN_TTL" default:"30m" validate:"gt=0"`
+	RefreshTokenTTL      time.Duration `yaml:"refreshTokenTTL" env:"REFRESH_TOKEN_TTL" default:"720h" validate:"gtfield=AccessTokenTTL"`
+	PasswordResetTTL     time.Duration `yaml:"passwordResetTTL" env:"PASSWORD_RESET_TTL" default:"15m" validate:"gt=0"`
+	EmailVerificationTTL time.Duration `yaml:"emailVerificationTTL" env:"EMAIL_VERIFICATION_TTL" default:"24h" validate:"gt=0"`
+	SecureCookies        bool          `yaml:"secureCookies" env:"SECURE_COOKIES"`
+}
+
+type MailConfig struct {
+	Backend      string       `yaml:"backend" env:"MAIL_BACKEND" default:"smtp" validate:"oneof=smtp file memory"`
+	From         string       `yaml:"from" env:"MAIL_FROM" validate:"required_if=Backend smtp,omitempty,email"`
+	Dir          string       `yaml:"dir" env:"MAIL_DIR" default:"./tmp/mail" validate:"required_if=Backend file"`
+	TemplatesDir string       `yaml:"templatesDir" env:"MAIL_TEMPLATES_DIR" default:"./templates" validate:"required"`
+	FrontendURL  string       `yaml:"frontendURL" env:"FRONTEND_URL" default:"http://localhost:5173" validate:"required,url"`
+	SMTP         SMTPConfig   `yaml:"smtp"`
+	Outbox       OutboxConfig `yaml:"outbox"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST" default:"smtp.gmail.com" validate:"required"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" default:"465" validate:"min=1,max=65535"`
+	Username string `yaml:"username" env:"SMTP_USER"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	// TLS is "implicit" to dial with TLS (usually port 465) or "starttls" to
+	// upgrade a plain connection (usually port 587).
+	TLS                string `yaml:"tls" env:"SMTP_TLS" default:"implicit" validate:"oneof=implicit starttls"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" env:"SMTP_INSECURE_SKIP_VERIFY"`
+}
+
+type OutboxConfig struct {
+	Workers      int           `yaml:"workers" env:"OUTBOX_WORKERS" default:"4" validate:"min=1"`
+	BatchSize    int           `yaml:"batchSize" env:"OUTBOX_BATCH_SIZE" default:"20" validate:"min=1"`
+	PollInterval time.Duration `yaml:"pollInterval" env:"OUTBOX_POLL_INTERVAL" default:"5s" validate:"gt=0"`
+	MaxAttempts  int           `yaml:"maxAttempts" env:"OUTBOX_MAX_ATTEMPTS" default:"8" validate:"min=1"`
+	BaseBackoff  time.Duration `yaml:"baseBackoff" env:"OUTBOX_BASE_BACKOFF" default:"30s" validate:"gt=0"`
+	MaxBackoff   time.Duration `yaml:"maxBackoff" env:"OUTBOX_MAX_BACKOFF" default:"6h" validate:"gtfield=BaseBackoff"`
+}
+
+type ProductsConfig struct {
+	PageSize int `yaml:"pageSize" env:"PRODUCTS_PAGE_SIZE" default:"8" validate:"min=1"`
+}
+
+type InventoryConfig struct {
+	ReservationTTL time.Duration `yaml:"reservationTTL" env:"RESERVATION_TTL" default:"15m" validate:"gt=0"`
+	SweepInterval  time.Duration `yaml:"sweepInterval" env:"RESERVATION_SWEEP_INTERVAL" default:"1m" validate:"gt=0"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json" validate:"oneof=json text"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none" to disable tracing, "stdout" to print spans for local
+	// use or "otlp" to send them to an OTLP/HTTP collector at Endpoint.
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout otlp"`
+	Endpoint    string  `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" validate:"required_if=Exporter otlp,omitempty,url"`
+	ServiceName string  `yaml:"serviceName" env:"OTEL_SERVICE_NAME" default:"og-style" validate:"required"`
+	SampleRatio float64 `yaml:"sampleRatio" env:"TRACING_SAMPLE_RATIO" default:"1" validate:"gte=0,lte=1"`
+}
+
+type HealthConfig struct {
+	// CacheTTL is how long a readiness report is reused between probes.
+	CacheTTL            time.Duration `yaml:"cacheTTL" env:"HEALTH_CACHE_TTL" default:"5s" validate:"gte=0"`
+	DatabaseTimeout     time.Duration `yaml:"databaseTimeout" env:"HEALTH_DATABASE_TIMEOUT" default:"1s" validate:"gt=0"`
+	ImageStorageTimeout time.Duration `yaml:"imageStorageTimeout" env:"HEALTH_IMAGE_STORAGE_TIMEOUT" default:"3s" validate:"gt=0"`
+	MailerTimeout       time.Duration `yaml:"mailerTimeout" env:"HEALTH_MAILER_TIMEOUT" default:"3s" validate:"gt=0"`
+	// ImageStorageCacheTTL keeps a healthy image storage status longer than
+	// CacheTTL: its check spends Cloudinary Admin API quota.
+	ImageStorageCacheTTL time.Duration `yaml:"imageStorageCacheTTL" env:"HEALTH_IMAGE_STORAGE_CACHE_TTL" default:"5m" validate:"gte=0"`
+}
+
+type PaymentsConfig struct {
+	WebhookSecret string `yaml:"webhookSecret" env:"PAYMENT_WEBHOOK_SECRET" validate:"required"`
+	WebhookURL    string `yaml:"webhookURL" env:"PAYMENT_WEBHOOK_URL" validate:"omitempty,url"`
+	AutoConfirm   bool   `yaml:"autoConfirm" env:"PAYMENT_AUTO_CONFIRM"`
+}
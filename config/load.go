@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/creasty/defaults"
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Load builds the configuration from defaults, the YAML file at path (or at
+// $CONFIG_FILE when path is empty; the file is optional), .env and the
+// environment, and validates the result. Commands that need only part of the
+// configuration can limit validation to the named sections, e.g. "Database".
+func Load(path string, sections ...string) (*Config, error) {
+	var cfg Config
+
+	if err := defaults.Set(&cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config: .env: %w", err)
+	}
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	envNames := map[string]string{}
+	if err := loadEnv(reflect.ValueOf(&cfg).Elem(), "Config", envNames); err != nil {
+		return nil, err
+	}
+
+	if cfg.Env == "production" {
+		cfg.Auth.SecureCookies = true
+	}
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = cfg.Mail.SMTP.Username
+	}
+
+	if err := validate(&cfg, envNames, sections); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv overrides every field tagged with `env` whose variable is set and
+// records the variable name under the field's yaml namespace.
+func loadEnv(v reflect.Value, namespace string, envNames map[string]string) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := namespace + "." + yamlName(field)
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			if err := loadEnv(value, name, envNames); err != nil {
+				return err
+			}
+			continue
+		}
+
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		envNames[name] = env
+
+		raw, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("config: %s: %w", env, err)
+		}
+	}
+
+	return nil
+}
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		parts := strings.Split(raw, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		v.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+func validate(cfg *Config, envNames map[string]string, sections []string) error {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(yamlName)
+
+	err := validate.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	messages := make([]string, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		if !inSections(e.StructNamespace(), sections) {
+			continue
+		}
+
+		field := e.Namespace()
+		if env, ok := envNames[field]; ok {
+			field += " (" + env + ")"
+		}
+		messages = append(messages, fmt.Sprintf("%s %s", strings.TrimPrefix(field, "Config."), describe(e)))
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(messages, "\n  "))
+}
+func inSections(namespace string, sections []string) bool {
+	if len(sections) == 0 {
+		return true
+	}
+
+	for _, section := range sections {
+		if strings.HasPrefix(namespace, "Config."+section+".") || namespace == "Config."+section {
+			return true
+		}
+	}
+	return false
+}
+func describe(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(e.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + e.Param()
+	case "max", "lte":
+		return "must be at most " + e.Param()
+	case "gt":
+		return "must be greater than " + e.Param()
+	case "gte":
+		return "must be at least " + e.Param()
+	case "gtfield":
+		return "must be greater than " + e.Param()
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	default:
+		return fmt.Sprintf("failed the %q check", e.Tag())
+	}
+}
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/models"
+)
+
+type BrandStorage interface {
+	GetAll(ctx context.Context) ([]*models.Brand, error)
+	Create(ctx context.Context, name string) (int, error)
+}
+
+type BrandPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+func (b *BrandPgStorage) GetAll(ctx context.Context) ([]*models.Brand, error) {
+	ctx, cancel := b.Timeouts.apply(ctx, "BrandStorage.GetAll")
+	defer cancel()
+
+	brands := []*models.Brand{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, b.DB), &brands, `SELECT * FROM brands ORDER BY id ASC`); err != nil {
+		return brands, err
+	}
+
+	return brands, nil
+}
+
+// Create returns the ID of the brand called name, inserting it if it does not exist.
+func (b *BrandPgStorage) Create(ctx context.Context, name string) (int, error) {
+	ctx, cancel := b.Timeouts.apply(ctx, "BrandStorage.Create")
+	defer cancel()
+
+	var brandId int
+
+	if err := conn(ctx, b.DB).QueryRow(ctx, `WITH inserted AS (
+			INSERT INTO brands (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING id
+		) SELECT id FROM inserted UNION ALL SELECT id FROM brands WHERE name = $1 LIMIT 1`, name).Scan(&brandId); err != nil {
+		return 0, err
+	}
+
+	return brandId, nil
+}
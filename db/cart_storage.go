@@ -2,26 +2,154 @@ package db
 
 import (
 	"context"
+	"errors"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/models"
+	"og-style/types"
 )
 
 type CartStorage interface {
-	Create(userId int) error
-	Delete(userId int) error
+	Get(ctx context.Context, userId int) (*models.Cart, error)
+	Create(ctx context.Context, userId int) error
+	Delete(ctx context.Context, userId int) error
+	GetItems(ctx context.Context, cartId int) ([]*types.CartItem, error)
+	GetItem(ctx context.Context, cartId, itemId int) (*models.CartItem, error)
+	GetItemByVariant(ctx context.Context, cartId, variantId int) (*models.CartItem, error)
+	Lock(ctx context.Context, cartId int) error
+	AddItem(ctx context.Context, cartId, variantId int, data *types.AddCartItem) error
+	UpdateItemQuantity(ctx context.Context, cartId, itemId, quantity int) error
+	DeleteItem(ctx context.Context, cartId, itemId int) error
+	Clear(ctx context.Context, cartId int) error
 }
 
 type CartPgStorage struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
 }
 
-func (c *CartPgStorage) Create(userId int) error {
-	if _, err := c.DB.Query(context.Background(), `INSERT INTO cart (user_id) VALUES ($1)`, userId); err != nil {
+func (c *CartPgStorage) Get(ctx context.Context, userId int) (*models.Cart, error) {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.Get")
+	defer cancel()
+
+	var cart models.Cart
+
+	if err := pgxscan.Get(ctx, conn(ctx, c.DB), &cart, `SELECT * FROM cart WHERE user_id = $1`, userId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &cart, nil
+}
+func (c *CartPgStorage) Create(ctx context.Context, userId int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.Create")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `INSERT INTO cart (user_id) VALUES ($1)`, userId); err != nil {
+		return err
+	}
+	return nil
+}
+func (c *CartPgStorage) Delete(ctx context.Context, userId int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.Delete")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `DELETE FROM cart WHERE user_id = $1`, userId); err != nil {
+		return err
+	}
+	return nil
+}
+func (c *CartPgStorage) GetItems(ctx context.Context, cartId int) ([]*types.CartItem, error) {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.GetItems")
+	defer cancel()
+
+	items := []*types.CartItem{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, c.DB), &items, `SELECT ci.id, ci.product_id, ci.variant_id, p.name, p.images, p.price, p.discounted_price, ci.size, ci.color, ci.quantity
+		FROM cart_item ci JOIN product p ON ci.product_id = p.id
+		WHERE ci.cart_id = $1 ORDER BY ci.id ASC`, cartId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return items, err
+		}
+	}
+
+	return items, nil
+}
+func (c *CartPgStorage) GetItem(ctx context.Context, cartId, itemId int) (*models.CartItem, error) {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.GetItem")
+	defer cancel()
+
+	var item models.CartItem
+
+	if err := pgxscan.Get(ctx, conn(ctx, c.DB), &item, `SELECT * FROM cart_item WHERE id = $1 AND cart_id = $2`, itemId, cartId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+func (c *CartPgStorage) GetItemByVariant(ctx context.Context, cartId, variantId int) (*models.CartItem, error) {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.GetItemByVariant")
+	defer cancel()
+
+	var item models.CartItem
+
+	if err := pgxscan.Get(ctx, conn(ctx, c.DB), &item, `SELECT * FROM cart_item WHERE cart_id = $1 AND variant_id = $2`, cartId, variantId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &item, nil
+}
+
+// Lock holds the cart row until the surrounding transaction ends, so changes
+// that read a cart line and then write it run one after the other.
+func (c *CartPgStorage) Lock(ctx context.Context, cartId int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.Lock")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `SELECT id FROM cart WHERE id = $1 FOR UPDATE`, cartId); err != nil {
+		return err
+	}
+	return nil
+}
+func (c *CartPgStorage) AddItem(ctx context.Context, cartId, variantId int, data *types.AddCartItem) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.AddItem")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `INSERT INTO cart_item (cart_id, product_id, variant_id, size, color, quantity) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (cart_id, variant_id) DO UPDATE SET quantity = LEAST(cart_item.quantity + EXCLUDED.quantity, 99)`, cartId, data.ProductID, variantId, data.Size, data.Color, data.Quantity); err != nil {
+		return err
+	}
+	return nil
+}
+func (c *CartPgStorage) UpdateItemQuantity(ctx context.Context, cartId, itemId, quantity int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.UpdateItemQuantity")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `UPDATE cart_item SET quantity = $1 WHERE id = $2 AND cart_id = $3`, quantity, itemId, cartId); err != nil {
 		return err
 	}
 	return nil
 }
-func (c *CartPgStorage) Delete(userId int) error {
-	if _, err := c.DB.Query(context.Background(), `DELETE FROM cart WHERE user_id = $1`, userId); err != nil {
+func (c *CartPgStorage) DeleteItem(ctx context.Context, cartId, itemId int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.DeleteItem")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `DELETE FROM cart_item WHERE id = $1 AND cart_id = $2`, itemId, cartId); err != nil {
+		return err
+	}
+	return nil
+}
+func (c *CartPgStorage) Clear(ctx context.Context, cartId int) error {
+	ctx, cancel := c.Timeouts.apply(ctx, "CartStorage.Clear")
+	defer cancel()
+
+	if _, err := conn(ctx, c.DB).Exec(ctx, `DELETE FROM cart_item WHERE cart_id = $1`, cartId); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,4 @@
+-- The baseline tables may predate the migrations and hold production data,
+-- so rolling back the baseline leaves them in place. Drop them by hand to
+-- start from an empty database.
+SELECT 1;
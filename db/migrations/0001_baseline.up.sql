@@ -0,0 +1,45 @@
+-- The schema the application ran on before it managed its own migrations.
+-- Databases created back then already have these tables, so this migration
+-- only creates them on an empty database; later migrations bring both kinds
+-- of database to the same schema.
+CREATE TABLE IF NOT EXISTS users (
+    id                     SERIAL PRIMARY KEY,
+    email                  TEXT   NOT NULL UNIQUE,
+    password               TEXT   NOT NULL,
+    name                   TEXT,
+    avatar                 TEXT,
+    password_reset_expires TIMESTAMPTZ,
+    role                   TEXT[] NOT NULL DEFAULT '{user}'
+);
+
+CREATE TABLE IF NOT EXISTS token (
+    id            SERIAL PRIMARY KEY,
+    user_id       INT  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS cart (
+    id      SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS brands (
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS product (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT   NOT NULL,
+    description      TEXT   NOT NULL,
+    price            INT    NOT NULL,
+    discounted_price INT,
+    discount         INT,
+    images           TEXT[] NOT NULL DEFAULT '{}',
+    size             TEXT[] NOT NULL DEFAULT '{}',
+    category         TEXT   NOT NULL,
+    sub_category     TEXT   NOT NULL,
+    materials        TEXT[] NOT NULL DEFAULT '{}',
+    colors           TEXT[] NOT NULL DEFAULT '{}',
+    brand            INT    NOT NULL REFERENCES brands (id)
+);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS product_variant;
+
+DROP INDEX IF EXISTS product_colors_idx;
+DROP INDEX IF EXISTS product_size_idx;
+DROP INDEX IF EXISTS product_brand_idx;
+DROP INDEX IF EXISTS product_category_idx;
+
+DROP INDEX IF EXISTS brands_name_idx;
@@ -0,0 +1,16 @@
+CREATE UNIQUE INDEX IF NOT EXISTS brands_name_idx ON brands (name);
+
+CREATE INDEX IF NOT EXISTS product_category_idx ON product (category, sub_category);
+CREATE INDEX IF NOT EXISTS product_brand_idx ON product (brand);
+CREATE INDEX IF NOT EXISTS product_size_idx ON product USING GIN (size);
+CREATE INDEX IF NOT EXISTS product_colors_idx ON product USING GIN (colors);
+
+CREATE TABLE IF NOT EXISTS product_variant (
+    id         SERIAL PRIMARY KEY,
+    product_id INT  NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    size       TEXT NOT NULL,
+    color      TEXT NOT NULL,
+    sku        TEXT NOT NULL UNIQUE,
+    stock      INT  NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    UNIQUE (product_id, size, color)
+);
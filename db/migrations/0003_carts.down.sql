@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reservation;
+DROP TABLE IF EXISTS cart_item;
+
+DROP INDEX IF EXISTS cart_user_id_idx;
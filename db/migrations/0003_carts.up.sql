@@ -0,0 +1,25 @@
+CREATE UNIQUE INDEX IF NOT EXISTS cart_user_id_idx ON cart (user_id);
+
+CREATE TABLE IF NOT EXISTS cart_item (
+    id         SERIAL PRIMARY KEY,
+    cart_id    INT  NOT NULL REFERENCES cart (id) ON DELETE CASCADE,
+    product_id INT  NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    variant_id INT  NOT NULL REFERENCES product_variant (id) ON DELETE CASCADE,
+    size       TEXT NOT NULL,
+    color      TEXT NOT NULL,
+    quantity   INT  NOT NULL CHECK (quantity > 0),
+    UNIQUE (cart_id, variant_id)
+);
+
+-- Reserved units are already subtracted from product_variant.stock and are
+-- returned to it when the reservation is released or expires.
+CREATE TABLE IF NOT EXISTS reservation (
+    id         SERIAL PRIMARY KEY,
+    cart_id    INT         NOT NULL REFERENCES cart (id) ON DELETE CASCADE,
+    variant_id INT         NOT NULL REFERENCES product_variant (id) ON DELETE CASCADE,
+    quantity   INT         NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (cart_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS reservation_expires_at_idx ON reservation (expires_at);
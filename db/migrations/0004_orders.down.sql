@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS payment_event;
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS order_item;
+DROP TABLE IF EXISTS orders;
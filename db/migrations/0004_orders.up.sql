@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id         SERIAL PRIMARY KEY,
+    user_id    INT         NOT NULL REFERENCES users (id),
+    status     TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'shipped', 'delivered', 'cancelled')),
+    total      INT         NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id, created_at DESC);
+
+-- Order items snapshot the product, so they keep no references to the
+-- catalog and survive products and variants being deleted.
+CREATE TABLE IF NOT EXISTS order_item (
+    id         SERIAL PRIMARY KEY,
+    order_id   INT  NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id INT  NOT NULL,
+    variant_id INT  NOT NULL,
+    name       TEXT NOT NULL,
+    size       TEXT NOT NULL,
+    color      TEXT NOT NULL,
+    quantity   INT  NOT NULL CHECK (quantity > 0),
+    price      INT  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_item_order_id_idx ON order_item (order_id);
+
+CREATE TABLE IF NOT EXISTS payment (
+    id         SERIAL PRIMARY KEY,
+    order_id   INT         NOT NULL UNIQUE REFERENCES orders (id) ON DELETE CASCADE,
+    intent_id  TEXT        NOT NULL UNIQUE,
+    amount     INT         NOT NULL,
+    status     TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'authorized', 'succeeded', 'failed', 'refunded')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- payment_event makes webhook delivery idempotent: an event id is applied once.
+CREATE TABLE IF NOT EXISTS payment_event (
+    id          TEXT PRIMARY KEY,
+    intent_id   TEXT        NOT NULL,
+    status      TEXT        NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS token_user_id_idx;
+
+ALTER TABLE token
+    DROP COLUMN IF EXISTS last_used_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS ip,
+    DROP COLUMN IF EXISTS user_agent;
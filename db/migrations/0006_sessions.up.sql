@@ -0,0 +1,10 @@
+-- Refresh tokens used to be one per user; every device now has its own row.
+ALTER TABLE token DROP CONSTRAINT IF EXISTS token_user_id_key;
+
+ALTER TABLE token
+    ADD COLUMN IF NOT EXISTS user_agent   TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip           TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS token_user_id_idx ON token (user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_password_reset_token_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_token;
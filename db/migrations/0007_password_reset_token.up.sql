@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_token TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_password_reset_token_idx ON users (password_reset_token);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_verification_token_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS verification_expires,
+    DROP COLUMN IF EXISTS verification_token,
+    DROP COLUMN IF EXISTS verified_at;
@@ -0,0 +1,10 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS verified_at          TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS verification_token   TEXT,
+    ADD COLUMN IF NOT EXISTS verification_expires TIMESTAMPTZ;
+
+-- Accounts created before verification existed were never sent a link;
+-- treat them as verified so they can keep placing and paying for orders.
+UPDATE users SET verified_at = NOW() WHERE verified_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_verification_token_idx ON users (verification_token);
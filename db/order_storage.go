@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/apperrors"
+	"og-style/models"
+)
+
+var (
+	ErrEmptyCart          = apperrors.Conflict("cart_empty", "корзина пуста")
+	ErrOrderStatusChanged = apperrors.Conflict("order_status_changed", "статус заказа был изменен.Повторите попытку")
+)
+
+type OrderStorage interface {
+	Get(ctx context.Context, id int) (*models.Order, error)
+	GetAll(ctx context.Context, userId int) ([]*models.Order, error)
+	GetItems(ctx context.Context, orderId int) ([]*models.OrderItem, error)
+	CreateFromCart(ctx context.Context, userId, cartId int) (int, error)
+	UpdateStatus(ctx context.Context, id int, from, to models.OrderStatus, mail *models.Mail) error
+}
+
+type OrderPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+func (o *OrderPgStorage) Get(ctx context.Context, id int) (*models.Order, error) {
+	ctx, cancel := o.Timeouts.apply(ctx, "OrderStorage.Get")
+	defer cancel()
+
+	var order models.Order
+
+	if err := pgxscan.Get(ctx, conn(ctx, o.DB), &order, `SELECT * FROM orders WHERE id = $1`, id); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+func (o *OrderPgStorage) GetAll(ctx context.Context, userId int) ([]*models.Order, error) {
+	ctx, cancel := o.Timeouts.apply(ctx, "OrderStorage.GetAll")
+	defer cancel()
+
+	orders := []*models.Order{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, o.DB), &orders, `SELECT * FROM orders WHERE user_id = $1 ORDER BY created_at DESC`, userId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return orders, err
+		}
+	}
+
+	return orders, nil
+}
+func (o *OrderPgStorage) GetItems(ctx context.Context, orderId int) ([]*models.OrderItem, error) {
+	ctx, cancel := o.Timeouts.apply(ctx, "OrderStorage.GetItems")
+	defer cancel()
+
+	items := []*models.OrderItem{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, o.DB), &items, `SELECT * FROM order_item WHERE order_id = $1 ORDER BY id ASC`, orderId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return items, err
+		}
+	}
+
+	return items, nil
+}
+
+// CreateFromCart moves every cart item into a new pending order, snapshotting
+// the current product name and price, and empties the cart in the same transaction.
+// Stock held by the cart's reservations is consumed; any quantity that is not
+// covered by a reservation is taken from the variant stock here.
+func (o *OrderPgStorage) CreateFromCart(ctx context.Context, userId, cartId int) (int, error) {
+	ctx, cancel := o.Timeouts.apply(ctx, "OrderStorage.CreateFromCart")
+	defer cancel()
+
+	tx, err := conn(ctx, o.DB).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT id FROM reservation WHERE cart_id = $1 FOR UPDATE`, cartId); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT ci.variant_id, ci.quantity - COALESCE(r.quantity, 0)
+		FROM cart_item ci LEFT JOIN reservation r ON r.cart_id = ci.cart_id AND r.variant_id = ci.variant_id
+		WHERE ci.cart_id = $1 ORDER BY ci.variant_id ASC
+		FOR UPDATE OF ci`, cartId)
+	if err != nil {
+		return 0, err
+	}
+
+	deficits := [][2]int{}
+	for rows.Next() {
+		var variantId, deficit int
+		if err := rows.Scan(&variantId, &deficit); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		deficits = append(deficits, [2]int{variantId, deficit})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(deficits) == 0 {
+		return 0, ErrEmptyCart
+	}
+
+	for _, d := range deficits {
+		if d[1] == 0 {
+			continue
+		}
+
+		tag, err := tx.Exec(ctx, `UPDATE product_variant SET stock = stock - $1 WHERE id = $2 AND stock >= $1`, d[1], d[0])
+		if err != nil {
+			return 0, err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return 0, ErrOutOfStock
+		}
+	}
+
+	var orderId int
+	if err := tx.QueryRow(ctx, `INSERT INTO orders (user_id, status) VALUES ($1, $2) RETURNING id`, userId, models.OrderPending).Scan(&orderId); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO order_item (order_id, product_id, variant_id, name, size, color, quantity, price)
+		SELECT $1, ci.product_id, ci.variant_id, p.name, ci.size, ci.color, ci.quantity, COALESCE(p.discounted_price, p.price)
+		FROM cart_item ci JOIN product p ON ci.product_id = p.id
+		WHERE ci.cart_id = $2 ORDER BY ci.id ASC`, orderId, cartId); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE orders SET total = (SELECT SUM(price * quantity) FROM order_item WHERE order_id = $1) WHERE id = $1`, orderId); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM cart_item WHERE cart_id = $1`, cartId); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM reservation WHERE cart_id = $1`, cartId); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
+}
+
+// UpdateStatus moves the order from one status to another, returning the
+// ordered stock to its variants when the order is cancelled. A non-nil mail
+// is queued in the email outbox in the same transaction.
+func (o *OrderPgStorage) UpdateStatus(ctx context.Context, id int, from, to models.OrderStatus, mail *models.Mail) error {
+	ctx, cancel := o.Timeouts.apply(ctx, "OrderStorage.UpdateStatus")
+	defer cancel()
+
+	tx, err := conn(ctx, o.DB).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`, to, id, from)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrOrderStatusChanged
+	}
+
+	if to == models.OrderCancelled {
+		if _, err := tx.Exec(ctx, `UPDATE product_variant v SET stock = v.stock + oi.quantity
+			FROM (SELECT variant_id, SUM(quantity) AS quantity FROM order_item WHERE order_id = $1 GROUP BY variant_id) oi
+			WHERE v.id = oi.variant_id`, id); err != nil {
+			return err
+		}
+	}
+
+	if mail != nil {
+		if err := enqueueEmail(ctx, tx, *mail); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/models"
+	"time"
+)
+
+type OutboxStorage interface {
+	Enqueue(ctx context.Context, mail models.Mail) error
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error)
+	MarkSent(ctx context.Context, id int) error
+	MarkFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time, dead bool) error
+}
+
+type OutboxPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// enqueueEmail inserts mail into the outbox through q, which lets storages
+// write the email in the same transaction as the change that caused it.
+func enqueueEmail(ctx context.Context, q execer, mail models.Mail) error {
+	if _, err := q.Exec(ctx, `INSERT INTO email_outbox (recipients, subject, html, text) VALUES ($1, $2, $3, $4)`, mail.To, mail.Subject, mail.HTML, mail.Text); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *OutboxPgStorage) Enqueue(ctx context.Context, mail models.Mail) error {
+	ctx, cancel := o.Timeouts.apply(ctx, "OutboxStorage.Enqueue")
+	defer cancel()
+
+	return enqueueEmail(ctx, conn(ctx, o.DB), mail)
+}
+
+// Claim leases up to limit due emails. A leased email is not returned again
+// until the lease expires, so several workers or instances can drain the
+// outbox without sending an email twice.
+func (o *OutboxPgStorage) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEmail, error) {
+	ctx, cancel := o.Timeouts.apply(ctx, "OutboxStorage.Claim")
+	defer cancel()
+
+	emails := []*models.OutboxEmail{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, o.DB), &emails, `UPDATE email_outbox SET locked_until = $1, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = $2 AND next_attempt_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY id ASC LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) RETURNING *`, time.Now().Add(lease), models.OutboxPending, limit); err != nil {
+		return emails, err
+	}
+
+	return emails, nil
+}
+func (o *OutboxPgStorage) MarkSent(ctx context.Context, id int) error {
+	ctx, cancel := o.Timeouts.apply(ctx, "OutboxStorage.MarkSent")
+	defer cancel()
+
+	if _, err := conn(ctx, o.DB).Exec(ctx, `UPDATE email_outbox SET status = $1, sent_at = NOW(), locked_until = NULL, last_error = NULL WHERE id = $2`, models.OutboxSent, id); err != nil {
+		return err
+	}
+	return nil
+}
+func (o *OutboxPgStorage) MarkFailed(ctx context.Context, id int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	ctx, cancel := o.Timeouts.apply(ctx, "OutboxStorage.MarkFailed")
+	defer cancel()
+
+	status := models.OutboxPending
+	if dead {
+		status = models.OutboxDead
+	}
+
+	if _, err := conn(ctx, o.DB).Exec(ctx, `UPDATE email_outbox SET status = $1, last_error = $2, next_attempt_at = $3, locked_until = NULL WHERE id = $4`, status, lastError, nextAttemptAt, id); err != nil {
+		return err
+	}
+	return nil
+}
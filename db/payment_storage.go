@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/apperrors"
+	"og-style/models"
+)
+
+var (
+	ErrPaymentStatusChanged = apperrors.Conflict("payment_status_changed", "статус платежа был изменен.Повторите попытку")
+	ErrPaymentExists        = apperrors.Conflict("payment_exists", "для заказа уже создан платеж")
+)
+
+type PaymentStorage interface {
+	GetByOrder(ctx context.Context, orderId int) (*models.Payment, error)
+	GetByIntent(ctx context.Context, intentId string) (*models.Payment, error)
+	Create(ctx context.Context, orderId int, intentId string, amount int) error
+	UpdateStatus(ctx context.Context, id int, status models.PaymentStatus) error
+	ApplyEvent(ctx context.Context, eventId, intentId string, status models.PaymentStatus) (bool, error)
+}
+
+type PaymentPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+func (p *PaymentPgStorage) GetByOrder(ctx context.Context, orderId int) (*models.Payment, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "PaymentStorage.GetByOrder")
+	defer cancel()
+
+	var payment models.Payment
+
+	if err := pgxscan.Get(ctx, conn(ctx, p.DB), &payment, `SELECT * FROM payment WHERE order_id = $1`, orderId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &payment, nil
+}
+func (p *PaymentPgStorage) GetByIntent(ctx context.Context, intentId string) (*models.Payment, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "PaymentStorage.GetByIntent")
+	defer cancel()
+
+	var payment models.Payment
+
+	if err := pgxscan.Get(ctx, conn(ctx, p.DB), &payment, `SELECT * FROM payment WHERE intent_id = $1`, intentId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &payment, nil
+}
+
+// Create records a pending payment for the order. Only a failed payment is
+// replaced; any other one returns ErrPaymentExists, so a live intent is never
+// orphaned by a second one.
+func (p *PaymentPgStorage) Create(ctx context.Context, orderId int, intentId string, amount int) error {
+	ctx, cancel := p.Timeouts.apply(ctx, "PaymentStorage.Create")
+	defer cancel()
+
+	tag, err := conn(ctx, p.DB).Exec(ctx, `INSERT INTO payment (order_id, intent_id, amount, status) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (order_id) DO UPDATE SET intent_id = EXCLUDED.intent_id, amount = EXCLUDED.amount, status = EXCLUDED.status, updated_at = NOW()
+		WHERE payment.status = $5`, orderId, intentId, amount, models.PaymentPending, models.PaymentFailed)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrPaymentExists
+	}
+	return nil
+}
+func (p *PaymentPgStorage) UpdateStatus(ctx context.Context, id int, status models.PaymentStatus) error {
+	ctx, cancel := p.Timeouts.apply(ctx, "PaymentStorage.UpdateStatus")
+	defer cancel()
+
+	tag, err := conn(ctx, p.DB).Exec(ctx, `UPDATE payment SET status = $1, updated_at = NOW() WHERE id = $2 AND status = ANY($3)`, status, id, models.PaymentStatusesBefore(status))
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrPaymentStatusChanged
+	}
+	return nil
+}
+
+// ApplyEvent records a webhook event and moves the payment to status in one
+// transaction. A succeeded payment also marks its pending order as paid. It
+// returns false without changing anything if the event was already recorded.
+// Events that arrive out of order are recorded but leave the payment alone:
+// the status only moves forward, e.g. a late authorized event never turns a
+// succeeded payment back.
+func (p *PaymentPgStorage) ApplyEvent(ctx context.Context, eventId, intentId string, status models.PaymentStatus) (bool, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "PaymentStorage.ApplyEvent")
+	defer cancel()
+
+	tx, err := conn(ctx, p.DB).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO payment_event (id, intent_id, status) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING`, eventId, intentId, status)
+	if err != nil {
+		return false, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	var orderId int
+	if err := tx.QueryRow(ctx, `UPDATE payment SET status = $1, updated_at = NOW() WHERE intent_id = $2 AND status = ANY($3) RETURNING order_id`, status, intentId, models.PaymentStatusesBefore(status)).Scan(&orderId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return false, err
+		}
+	}
+
+	if status == models.PaymentSucceeded && orderId != 0 {
+		if _, err := tx.Exec(ctx, `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2 AND status = $3`, models.OrderPaid, orderId, models.OrderPending); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -13,22 +13,28 @@ import (
 )
 
 type ProductStorage interface {
-	Get(id int) (models.Product, error)
-	GetAll(params types.GetProductsParams) ([]*models.Product, error)
-	Create(data *types.CreateProduct) error
-	Update(id int, data *types.UpdateProduct) error
-	Delete(id int) error
-	GetFilters(category string) (types.ProductFilters, error)
+	Get(ctx context.Context, id int) (models.Product, error)
+	GetAll(ctx context.Context, params types.GetProductsParams) ([]*models.Product, error)
+	Create(ctx context.Context, data *types.CreateProduct) (int, error)
+	Update(ctx context.Context, id int, data *types.UpdateProduct) error
+	Delete(ctx context.Context, id int) error
+	GetFilters(ctx context.Context, category string, inStock bool) (types.ProductFilters, error)
+	Reindex(ctx context.Context) error
 }
 
 type ProductPgStorage struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	PageSize int
+	Timeouts QueryTimeouts
 }
 
-func (p *ProductPgStorage) Get(id int) (models.Product, error) {
+func (p *ProductPgStorage) Get(ctx context.Context, id int) (models.Product, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.Get")
+	defer cancel()
+
 	var product models.Product
 
-	if err := pgxscan.Get(context.Background(), p.DB, &product, `SELECT * FROM product WHERE id = $1`, id); err != nil {
+	if err := pgxscan.Get(ctx, conn(ctx, p.DB), &product, `SELECT * FROM product WHERE id = $1`, id); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return product, err
 		}
@@ -36,13 +42,14 @@ func (p *ProductPgStorage) Get(id int) (models.Product, error) {
 
 	return product, nil
 }
-func (p *ProductPgStorage) GetAll(params types.GetProductsParams) ([]*models.Product, error) {
+func (p *ProductPgStorage) GetAll(ctx context.Context, params types.GetProductsParams) ([]*models.Product, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.GetAll")
+	defer cancel()
+
 	products := []*models.Product{}
 	query := `SELECT * FROM product`
 	args := make([]any, 0, 2)
-	limit, page := 8, 1
-
-	fmt.Println(params.Page)
+	limit, page := p.PageSize, 1
 
 	if params.Limit != 0 {
 		limit = params.Limit
@@ -104,10 +111,29 @@ func (p *ProductPgStorage) GetAll(params types.GetProductsParams) ([]*models.Pro
 		args = append(args, params.SubCategory)
 	}
 
+	if params.InStock {
+		clause := `EXISTS (SELECT 1 FROM product_variant v WHERE v.product_id = product.id AND v.stock > 0`
+		if len(params.Size) != 0 {
+			clause += fmt.Sprintf(` AND v.size = ANY($%d)`, len(args)+1)
+			args = append(args, params.Size)
+		}
+		if len(params.Colors) != 0 {
+			clause += fmt.Sprintf(` AND v.color = ANY($%d)`, len(args)+1)
+			args = append(args, params.Colors)
+		}
+		clause += `)`
+
+		if utils.IsContainsSubstring(query, "where") {
+			query += ` AND ` + clause
+		} else {
+			query += ` WHERE ` + clause
+		}
+	}
+
 	query += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
 	args = append(args, limit, (page*limit)-limit)
 
-	if err := pgxscan.Select(context.Background(), p.DB, &products, query, args...); err != nil {
+	if err := pgxscan.Select(ctx, conn(ctx, p.DB), &products, query, args...); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return products, err
 		}
@@ -115,23 +141,28 @@ func (p *ProductPgStorage) GetAll(params types.GetProductsParams) ([]*models.Pro
 
 	return products, nil
 }
-func (p *ProductPgStorage) Create(data *types.CreateProduct) error {
+func (p *ProductPgStorage) Create(ctx context.Context, data *types.CreateProduct) (int, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.Create")
+	defer cancel()
+
 	var discountedPrice int
 
 	if data.Discount != 0 {
 		discountedPrice = data.Price - ((data.Price * data.Discount) / 100)
 	}
 
-	if _, err := p.DB.Query(context.Background(), `INSERT INTO product (name,description,price,discounted_price,discount,images,size,category,sub_category,colors,brand,materials) VALUES ($1, $2, $3, CASE WHEN $4 = 0 THEN NULL ELSE $4 END,CASE WHEN $5 = 0 THEN NULL ELSE $5 END, $6, $7, $8, $9, $10, $11, $12)`, data.Name, data.Description, data.Price, discountedPrice, data.Discount, data.Images, data.Size, data.Category, data.SubCategory, data.Colors, data.Brand, data.Materials); err != nil {
-		fmt.Println(err)
-		return err
+	var productId int
+	if err := conn(ctx, p.DB).QueryRow(ctx, `INSERT INTO product (name,description,price,discounted_price,discount,images,size,category,sub_category,colors,brand,materials) VALUES ($1, $2, $3, CASE WHEN $4 = 0 THEN NULL ELSE $4 END,CASE WHEN $5 = 0 THEN NULL ELSE $5 END, $6, $7, $8, $9, $10, $11, $12) RETURNING id`, data.Name, data.Description, data.Price, discountedPrice, data.Discount, data.Images, data.Size, data.Category, data.SubCategory, data.Colors, data.Brand, data.Materials).Scan(&productId); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return productId, nil
 }
-func (p *ProductPgStorage) Update(id int, data *types.UpdateProduct) error {
+func (p *ProductPgStorage) Update(ctx context.Context, id int, data *types.UpdateProduct) error {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.Update")
+	defer cancel()
 
-	if _, err := p.DB.Query(context.Background(), `UPDATE product p SET 
+	if _, err := conn(ctx, p.DB).Exec(ctx, `UPDATE product p SET 
                      name=COALESCE(NULLIF($1,''), p.name),
                      description=COALESCE(NULLIF($2,''), p.description),
                      price=COALESCE(NULLIF($3,0), p.price),
@@ -153,23 +184,46 @@ func (p *ProductPgStorage) Update(id int, data *types.UpdateProduct) error {
 
 	return nil
 }
-func (p *ProductPgStorage) Delete(id int) error {
-	if _, err := p.DB.Query(context.Background(), `DELETE FROM product WHERE id = $1`, id); err != nil {
+func (p *ProductPgStorage) Delete(ctx context.Context, id int) error {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.Delete")
+	defer cancel()
+
+	if _, err := conn(ctx, p.DB).Exec(ctx, `DELETE FROM product WHERE id = $1`, id); err != nil {
 		return err
 	}
 	return nil
 }
-func (p *ProductPgStorage) GetFilters(category string) (types.ProductFilters, error) {
+func (p *ProductPgStorage) GetFilters(ctx context.Context, category string, inStock bool) (types.ProductFilters, error) {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.GetFilters")
+	defer cancel()
+
 	var productFilters types.ProductFilters
 
-	if err := pgxscan.Get(context.Background(), p.DB, &productFilters, ` SELECT
-     	ARRAY(SELECT DISTINCT UNNEST(size) as s FROM product WHERE category = $1 ORDER BY s ASC) as size,
-        ARRAY(SELECT DISTINCT UNNEST(colors) FROM product  WHERE category = $1) as colors,
-        (SELECT COALESCE(MIN(price),0) FROM product  WHERE category = $1) as min_price,
-        (SELECT COALESCE(MAX(price),0) FROM product  WHERE category = $1) as max_price,
-		ARRAY(SELECT DISTINCT b.id FROM product p JOIN brands b ON p.brand = b.id  WHERE category = $1 ) as brands_id,ARRAY(SELECT DISTINCT b.name FROM product p JOIN brands b ON p.brand = b.id  WHERE category = $1) as brands_name;`, category); err != nil {
+	if err := pgxscan.Get(ctx, conn(ctx, p.DB), &productFilters, `WITH p AS (
+			SELECT * FROM product WHERE category = $1 AND (NOT $2 OR EXISTS (SELECT 1 FROM product_variant v WHERE v.product_id = product.id AND v.stock > 0))
+		), v AS (
+			SELECT v.size, v.color FROM product_variant v JOIN p ON v.product_id = p.id WHERE v.stock > 0
+		) SELECT
+		ARRAY(SELECT DISTINCT s FROM (SELECT UNNEST(size) AS s FROM p WHERE NOT $2 UNION SELECT size FROM v WHERE $2) t ORDER BY s ASC) as size,
+		ARRAY(SELECT DISTINCT c FROM (SELECT UNNEST(colors) AS c FROM p WHERE NOT $2 UNION SELECT color FROM v WHERE $2) t) as colors,
+		(SELECT COALESCE(MIN(price),0) FROM p) as min_price,
+		(SELECT COALESCE(MAX(price),0) FROM p) as max_price,
+		ARRAY(SELECT DISTINCT b.id FROM p JOIN brands b ON p.brand = b.id) as brands_id,
+		ARRAY(SELECT DISTINCT b.name FROM p JOIN brands b ON p.brand = b.id) as brands_name;`, category, inStock); err != nil {
 		return productFilters, err
 	}
 
 	return productFilters, nil
 }
+
+// Reindex rebuilds the catalog indexes used by product search and filters and
+// refreshes the planner statistics, e.g. after a bulk import.
+func (p *ProductPgStorage) Reindex(ctx context.Context) error {
+	ctx, cancel := p.Timeouts.apply(ctx, "ProductStorage.Reindex")
+	defer cancel()
+
+	if _, err := conn(ctx, p.DB).Exec(ctx, `REINDEX TABLE product; REINDEX TABLE product_variant; ANALYZE product, product_variant, brands`); err != nil {
+		return err
+	}
+	return nil
+}
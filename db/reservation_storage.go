@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/apperrors"
+	"time"
+)
+
+var ErrOutOfStock = apperrors.Conflict("out_of_stock", "недостаточно товара на складе")
+
+type ReservationStorage interface {
+	Reserve(ctx context.Context, cartId, variantId, quantity int, ttl time.Duration) error
+	Release(ctx context.Context, cartId, variantId int) error
+	ReleaseAll(ctx context.Context, cartId int) error
+	ReleaseExpired(ctx context.Context) (int, error)
+}
+
+type ReservationPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+// Reserve sets the amount of variant stock held for the cart to quantity,
+// taking the difference from (or returning it to) product_variant.stock
+// while the variant row is locked.
+func (r *ReservationPgStorage) Reserve(ctx context.Context, cartId, variantId, quantity int, ttl time.Duration) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "ReservationStorage.Reserve")
+	defer cancel()
+
+	tx, err := conn(ctx, r.DB).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var stock int
+	if err := tx.QueryRow(ctx, `SELECT stock FROM product_variant WHERE id = $1 FOR UPDATE`, variantId).Scan(&stock); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOutOfStock
+		}
+		return err
+	}
+
+	var reserved int
+	if err := tx.QueryRow(ctx, `SELECT quantity FROM reservation WHERE cart_id = $1 AND variant_id = $2`, cartId, variantId).Scan(&reserved); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+
+	delta := quantity - reserved
+	if delta > stock {
+		return ErrOutOfStock
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE product_variant SET stock = stock - $1 WHERE id = $2`, delta, variantId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO reservation (cart_id, variant_id, quantity, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cart_id, variant_id) DO UPDATE SET quantity = EXCLUDED.quantity, expires_at = EXCLUDED.expires_at`, cartId, variantId, quantity, time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+func (r *ReservationPgStorage) Release(ctx context.Context, cartId, variantId int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "ReservationStorage.Release")
+	defer cancel()
+
+	if _, err := conn(ctx, r.DB).Exec(ctx, `WITH released AS (
+			DELETE FROM reservation WHERE cart_id = $1 AND variant_id = $2 RETURNING variant_id, quantity
+		) UPDATE product_variant v SET stock = v.stock + released.quantity FROM released WHERE v.id = released.variant_id`, cartId, variantId); err != nil {
+		return err
+	}
+	return nil
+}
+func (r *ReservationPgStorage) ReleaseAll(ctx context.Context, cartId int) error {
+	ctx, cancel := r.Timeouts.apply(ctx, "ReservationStorage.ReleaseAll")
+	defer cancel()
+
+	if _, err := conn(ctx, r.DB).Exec(ctx, `WITH released AS (
+			DELETE FROM reservation WHERE cart_id = $1 RETURNING variant_id, quantity
+		) UPDATE product_variant v SET stock = v.stock + released.quantity FROM released WHERE v.id = released.variant_id`, cartId); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReleaseExpired returns the stock of every expired reservation and reports
+// how many reservations were released.
+func (r *ReservationPgStorage) ReleaseExpired(ctx context.Context) (int, error) {
+	ctx, cancel := r.Timeouts.apply(ctx, "ReservationStorage.ReleaseExpired")
+	defer cancel()
+
+	var released int
+
+	if err := conn(ctx, r.DB).QueryRow(ctx, `WITH expired AS (
+			DELETE FROM reservation WHERE expires_at < NOW() RETURNING variant_id, quantity
+		), restocked AS (
+			UPDATE product_variant v SET stock = v.stock + e.quantity
+			FROM (SELECT variant_id, SUM(quantity) AS quantity FROM expired GROUP BY variant_id) e
+			WHERE v.id = e.variant_id
+		) SELECT COUNT(*) FROM expired`).Scan(&released); err != nil {
+		return 0, err
+	}
+
+	return released, nil
+}
@@ -1 +1,27 @@
 package db
+
+import (
+	"context"
+	"time"
+)
+
+// QueryTimeouts bounds how long a storage method may spend in the database.
+// Operations are keyed by interface and method name, e.g.
+// "ProductStorage.GetAll", and fall back to Default; a zero timeout leaves
+// the caller's deadline as the only limit.
+type QueryTimeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+func (t QueryTimeouts) apply(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout, ok := t.Operations[operation]
+	if !ok {
+		timeout = t.Default
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -10,18 +10,26 @@ import (
 )
 
 type TokenStorage interface {
-	Get(userId int) (*models.Token, error)
-	Create(userId int, token string) error
+	Get(ctx context.Context, id int) (*models.Token, error)
+	GetAll(ctx context.Context, userId int) ([]*models.Token, error)
+	Create(ctx context.Context, userId int, userAgent, ip string) (int, error)
+	Rotate(ctx context.Context, id int, oldToken, newToken string) (bool, error)
+	Delete(ctx context.Context, userId, id int) error
+	DeleteAll(ctx context.Context, userId int) error
 }
 
 type TokenPgStorage struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
 }
 
-func (t *TokenPgStorage) Get(userId int) (*models.Token, error) {
+func (t *TokenPgStorage) Get(ctx context.Context, id int) (*models.Token, error) {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.Get")
+	defer cancel()
+
 	var token models.Token
 
-	if err := pgxscan.Get(context.Background(), t.DB, &token, `SELECT * FROM token WHERE user_id = $1`, userId); err != nil {
+	if err := pgxscan.Get(ctx, conn(ctx, t.DB), &token, `SELECT * FROM token WHERE id = $1`, id); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return nil, err
 		}
@@ -29,8 +37,61 @@ func (t *TokenPgStorage) Get(userId int) (*models.Token, error) {
 
 	return &token, nil
 }
-func (t *TokenPgStorage) Create(userId int, token string) error {
-	if _, err := t.DB.Query(context.Background(), "INSERT INTO token (user_id, refresh_token) VALUES ($1, $2)", userId, token); err != nil {
+func (t *TokenPgStorage) GetAll(ctx context.Context, userId int) ([]*models.Token, error) {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.GetAll")
+	defer cancel()
+
+	tokens := []*models.Token{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, t.DB), &tokens, `SELECT * FROM token WHERE user_id = $1 ORDER BY last_used_at DESC`, userId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return tokens, err
+		}
+	}
+
+	return tokens, nil
+}
+func (t *TokenPgStorage) Create(ctx context.Context, userId int, userAgent, ip string) (int, error) {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.Create")
+	defer cancel()
+
+	var tokenId int
+
+	if err := conn(ctx, t.DB).QueryRow(ctx, "INSERT INTO token (user_id, refresh_token, user_agent, ip) VALUES ($1, '', $2, $3) RETURNING id", userId, userAgent, ip).Scan(&tokenId); err != nil {
+		return 0, err
+	}
+
+	return tokenId, nil
+}
+
+// Rotate replaces the session's refresh token only if it still equals oldToken,
+// so a token can be exchanged at most once.
+func (t *TokenPgStorage) Rotate(ctx context.Context, id int, oldToken, newToken string) (bool, error) {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.Rotate")
+	defer cancel()
+
+	tag, err := conn(ctx, t.DB).Exec(ctx, `UPDATE token SET refresh_token = $1, last_used_at = NOW() WHERE id = $2 AND refresh_token = $3`, newToken, id, oldToken)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+func (t *TokenPgStorage) Delete(ctx context.Context, userId, id int) error {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.Delete")
+	defer cancel()
+
+	if _, err := conn(ctx, t.DB).Exec(ctx, `DELETE FROM token WHERE id = $1 AND user_id = $2`, id, userId); err != nil {
+		return err
+	}
+
+	return nil
+}
+func (t *TokenPgStorage) DeleteAll(ctx context.Context, userId int) error {
+	ctx, cancel := t.Timeouts.apply(ctx, "TokenStorage.DeleteAll")
+	defer cancel()
+
+	if _, err := conn(ctx, t.DB).Exec(ctx, `DELETE FROM token WHERE user_id = $1`, userId); err != nil {
 		return err
 	}
 
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TxManager runs a unit of work in one transaction. Storage methods called
+// with the context passed to f join that transaction instead of using the pool.
+type TxManager interface {
+	WithinTx(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+// PgTxManager begins transactions on DB. A WithinTx call made inside another
+// one runs in a savepoint, so only its own work is rolled back when it fails.
+// The outermost call is retried up to MaxRetries times when PostgreSQL aborts
+// it with a serialization failure or a deadlock, so f must be safe to run
+// again and should keep side effects outside the database until it returns.
+type PgTxManager struct {
+	DB         *pgxpool.Pool
+	Options    pgx.TxOptions
+	MaxRetries int
+}
+
+type txKey struct{}
+
+// querier is what storages need from either the pool or a transaction.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// conn returns the transaction ctx runs in, falling back to pool. Storages
+// that begin their own transaction get a savepoint when there is one.
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+func (m *PgTxManager) WithinTx(ctx context.Context, f func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return runTx(ctx, f, func() (pgx.Tx, error) { return tx.Begin(ctx) })
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, f, func() (pgx.Tx, error) { return m.DB.BeginTx(ctx, m.Options) })
+		if err == nil || attempt >= m.MaxRetries || !retryable(err) {
+			return err
+		}
+	}
+}
+func runTx(ctx context.Context, f func(ctx context.Context) error, begin func() (pgx.Tx, error)) error {
+	tx, err := begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := f(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// retryable reports whether err aborted the transaction only because of
+// concurrent transactions, so that running it again may succeed.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	// serialization_failure, deadlock_detected
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
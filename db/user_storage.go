@@ -5,30 +5,46 @@ import (
 	"errors"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/apperrors"
 	"og-style/models"
 	"og-style/types"
 	"time"
 )
 
+// ErrUserExists is returned by Create when another sign-up took the email
+// between the caller's lookup and the insert.
+var ErrUserExists = apperrors.Conflict("user_exists", "пользователь с такой эл.почтой уже существует")
+
 type UserStorage interface {
-	Get(id int) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	Create(data *types.CreateUser) (int, error)
+	Get(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, data *types.CreateUser) (int, error)
 	//Update(data *types.UpdateUser) error
-	UpdatePassword(userId int, password string) error
-	UpdatePasswordExpires(userId int, passwordResetExpires time.Time) error
-	DeletePasswordResetExpires(userId int) error
+	GetByPasswordResetToken(ctx context.Context, token string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userId int, password string, mail models.Mail) error
+	SetVerificationToken(ctx context.Context, userId int, token string, expires time.Time, mails ...models.Mail) error
+	VerifyEmail(ctx context.Context, token string) (int, error)
+	SetPasswordResetToken(ctx context.Context, userId int, token string, expires time.Time, mail models.Mail) error
+	ResetPassword(ctx context.Context, token, password string, mail models.Mail) (int, error)
+	MarkVerified(ctx context.Context, userId int) error
+	GrantRole(ctx context.Context, userId int, role string) error
+	RevokeRole(ctx context.Context, userId int, role string) error
 }
 
 type UserPgStorage struct {
-	DB *pgxpool.Pool
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
 }
 
-func (u *UserPgStorage) Get(id int) (*models.User, error) {
+func (u *UserPgStorage) Get(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.Get")
+	defer cancel()
+
 	var user models.User
 
-	if err := pgxscan.Get(context.Background(), u.DB, &user, `SELECT * FROM users WHERE id = $1`, id); err != nil {
+	if err := pgxscan.Get(ctx, conn(ctx, u.DB), &user, `SELECT * FROM users WHERE id = $1`, id); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return nil, err
 		}
@@ -36,10 +52,13 @@ func (u *UserPgStorage) Get(id int) (*models.User, error) {
 
 	return &user, nil
 }
-func (u *UserPgStorage) GetByEmail(email string) (*models.User, error) {
+func (u *UserPgStorage) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.GetByEmail")
+	defer cancel()
+
 	var user models.User
 
-	if err := pgxscan.Get(context.Background(), u.DB, &user, `SELECT * FROM users WHERE email = $1`, email); err != nil {
+	if err := pgxscan.Get(ctx, conn(ctx, u.DB), &user, `SELECT * FROM users WHERE email = $1`, email); err != nil {
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return nil, err
 		}
@@ -47,45 +66,192 @@ func (u *UserPgStorage) GetByEmail(email string) (*models.User, error) {
 
 	return &user, nil
 }
-func (u *UserPgStorage) Create(data *types.CreateUser) (int, error) {
+func (u *UserPgStorage) Create(ctx context.Context, data *types.CreateUser) (int, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.Create")
+	defer cancel()
+
 	var userId int
 
-	if err := u.DB.QueryRow(context.Background(), "INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id ", data.Email, data.Password).Scan(&userId); err != nil {
+	if err := conn(ctx, u.DB).QueryRow(ctx, "INSERT INTO users (email, password, locale) VALUES ($1, $2, COALESCE(NULLIF($3, ''), 'ru')) RETURNING id ", data.Email, data.Password, data.Locale).Scan(&userId); err != nil {
+		var pgErr *pgconn.PgError
+		// unique_violation
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, ErrUserExists
+		}
 		return 0, err
 	}
 
 	return userId, nil
 }
-func (u *UserPgStorage) UpdatePassword(userId int, password string) error {
+func (u *UserPgStorage) GetByPasswordResetToken(ctx context.Context, token string) (*models.User, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.GetByPasswordResetToken")
+	defer cancel()
 
-	if _, err := u.DB.Query(context.Background(), "UPDATE users SET password = $1 WHERE id = $2", password, userId); err != nil {
+	var user models.User
+
+	if err := pgxscan.Get(ctx, conn(ctx, u.DB), &user, `SELECT * FROM users WHERE password_reset_token = $1 AND password_reset_expires > NOW()`, token); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// UpdatePassword sets the password, invalidates any pending reset token and
+// queues mail in the email outbox in one transaction.
+func (u *UserPgStorage) UpdatePassword(ctx context.Context, userId int, password string, mail models.Mail) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.UpdatePassword")
+	defer cancel()
+
+	tx, err := conn(ctx, u.DB).Begin(ctx)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	return nil
+	if _, err := tx.Exec(ctx, "UPDATE users SET password = $1, password_reset_token = NULL, password_reset_expires = NULL WHERE id = $2", password, userId); err != nil {
+		return err
+	}
+
+	if err := enqueueEmail(ctx, tx, mail); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
-func (u *UserPgStorage) UpdatePasswordExpires(userId int, passwordResetExpires time.Time) error {
-	if _, err := u.DB.Query(context.Background(), `UPDATE users SET password_reset_expires = $1 WHERE id = $2`, passwordResetExpires, userId); err != nil {
+
+// SetVerificationToken stores the token and queues mails in the email outbox
+// in one transaction.
+func (u *UserPgStorage) SetVerificationToken(ctx context.Context, userId int, token string, expires time.Time, mails ...models.Mail) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.SetVerificationToken")
+	defer cancel()
+
+	tx, err := conn(ctx, u.DB).Begin(ctx)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	return nil
+	if _, err := tx.Exec(ctx, `UPDATE users SET verification_token = $1, verification_expires = $2 WHERE id = $3 AND verified_at IS NULL`, token, expires, userId); err != nil {
+		return err
+	}
+
+	for _, mail := range mails {
+		if err := enqueueEmail(ctx, tx, mail); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// VerifyEmail marks the owner of an unexpired verification token as verified
+// and clears the token. It returns 0 if no user matched.
+func (u *UserPgStorage) VerifyEmail(ctx context.Context, token string) (int, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.VerifyEmail")
+	defer cancel()
+
+	var userId int
+
+	if err := conn(ctx, u.DB).QueryRow(ctx, `UPDATE users SET verified_at = NOW(), verification_token = NULL, verification_expires = NULL
+		WHERE verification_token = $1 AND verification_expires > NOW() RETURNING id`, token).Scan(&userId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return 0, err
+		}
+	}
+
+	return userId, nil
 }
 
-//func (u *UserPgStorage) Update(data *types.UpdateUser) error {
+// SetPasswordResetToken stores the token and queues mail in the email outbox
+// in one transaction.
+func (u *UserPgStorage) SetPasswordResetToken(ctx context.Context, userId int, token string, expires time.Time, mail models.Mail) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.SetPasswordResetToken")
+	defer cancel()
+
+	tx, err := conn(ctx, u.DB).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_reset_token = $1, password_reset_expires = $2 WHERE id = $3`, token, expires, userId); err != nil {
+		return err
+	}
+
+	if err := enqueueEmail(ctx, tx, mail); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ResetPassword sets the password of the user owning an unexpired reset token,
+// clears the token and queues mail in one transaction. It returns 0 if no user
+// matched.
+func (u *UserPgStorage) ResetPassword(ctx context.Context, token, password string, mail models.Mail) (int, error) {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.ResetPassword")
+	defer cancel()
+
+	tx, err := conn(ctx, u.DB).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userId int
+	if err := tx.QueryRow(ctx, `UPDATE users SET password = $1, password_reset_token = NULL, password_reset_expires = NULL
+		WHERE password_reset_token = $2 AND password_reset_expires > NOW() RETURNING id`, password, token).Scan(&userId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if err := enqueueEmail(ctx, tx, mail); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
+
+// func (u *UserPgStorage) Update(ctx context.Context, data *types.UpdateUser) error {
 //
-//	if _,err := u.DB.Query(context.Background(), `UPDATE users Set email = COALESCE($1, email), password = COALESCE($2, password), name = COALESCE($3, name), avatar = COALESCE($4, avatar)`, data.Email,data.Password,data.Name,data.Email); err != nil {
-//		return err
-//	}
+//		if _,err := conn(ctx, u.DB).Query(ctx, `UPDATE users Set email = COALESCE($1, email), password = COALESCE($2, password), name = COALESCE($3, name), avatar = COALESCE($4, avatar)`, data.Email,data.Password,data.Name,data.Email); err != nil {
+//			return err
+//		}
 //
-//	return nil
-//}
+//		return nil
+//	}
+func (u *UserPgStorage) MarkVerified(ctx context.Context, userId int) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.MarkVerified")
+	defer cancel()
 
-func (u *UserPgStorage) DeletePasswordResetExpires(userId int) error {
+	if _, err := conn(ctx, u.DB).Exec(ctx, `UPDATE users SET verified_at = COALESCE(verified_at, NOW()), verification_token = NULL, verification_expires = NULL WHERE id = $1`, userId); err != nil {
+		return err
+	}
+	return nil
+}
+func (u *UserPgStorage) GrantRole(ctx context.Context, userId int, role string) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.GrantRole")
+	defer cancel()
 
-	if _, err := u.DB.Query(context.Background(), `UPDATE users SET password_reset_expires = null WHERE id = $1`, userId); err != nil {
+	if _, err := conn(ctx, u.DB).Exec(ctx, `UPDATE users SET role = array_append(role, $1) WHERE id = $2 AND NOT ($1 = ANY(role))`, role, userId); err != nil {
 		return err
 	}
+	return nil
+}
+func (u *UserPgStorage) RevokeRole(ctx context.Context, userId int, role string) error {
+	ctx, cancel := u.Timeouts.apply(ctx, "UserStorage.RevokeRole")
+	defer cancel()
 
+	if _, err := conn(ctx, u.DB).Exec(ctx, `UPDATE users SET role = array_remove(role, $1) WHERE id = $2`, role, userId); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/models"
+	"og-style/types"
+)
+
+type VariantStorage interface {
+	Get(ctx context.Context, productId, id int) (*models.ProductVariant, error)
+	GetAll(ctx context.Context, productId int) ([]*models.ProductVariant, error)
+	GetBySKU(ctx context.Context, sku string) (*models.ProductVariant, error)
+	GetByOptions(ctx context.Context, productId int, size, color string) (*models.ProductVariant, error)
+	Create(ctx context.Context, productId int, data *types.CreateVariant) (int, error)
+	Update(ctx context.Context, productId, id int, data *types.UpdateVariant) error
+	Delete(ctx context.Context, productId, id int) error
+}
+
+type VariantPgStorage struct {
+	DB       *pgxpool.Pool
+	Timeouts QueryTimeouts
+}
+
+func (v *VariantPgStorage) Get(ctx context.Context, productId, id int) (*models.ProductVariant, error) {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.Get")
+	defer cancel()
+
+	var variant models.ProductVariant
+
+	if err := pgxscan.Get(ctx, conn(ctx, v.DB), &variant, `SELECT * FROM product_variant WHERE id = $1 AND product_id = $2`, id, productId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &variant, nil
+}
+func (v *VariantPgStorage) GetAll(ctx context.Context, productId int) ([]*models.ProductVariant, error) {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.GetAll")
+	defer cancel()
+
+	variants := []*models.ProductVariant{}
+
+	if err := pgxscan.Select(ctx, conn(ctx, v.DB), &variants, `SELECT * FROM product_variant WHERE product_id = $1 ORDER BY id ASC`, productId); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return variants, err
+		}
+	}
+
+	return variants, nil
+}
+func (v *VariantPgStorage) GetBySKU(ctx context.Context, sku string) (*models.ProductVariant, error) {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.GetBySKU")
+	defer cancel()
+
+	var variant models.ProductVariant
+
+	if err := pgxscan.Get(ctx, conn(ctx, v.DB), &variant, `SELECT * FROM product_variant WHERE sku = $1`, sku); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &variant, nil
+}
+func (v *VariantPgStorage) GetByOptions(ctx context.Context, productId int, size, color string) (*models.ProductVariant, error) {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.GetByOptions")
+	defer cancel()
+
+	var variant models.ProductVariant
+
+	if err := pgxscan.Get(ctx, conn(ctx, v.DB), &variant, `SELECT * FROM product_variant WHERE product_id = $1 AND size = $2 AND color = $3`, productId, size, color); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return &variant, nil
+}
+func (v *VariantPgStorage) Create(ctx context.Context, productId int, data *types.CreateVariant) (int, error) {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.Create")
+	defer cancel()
+
+	var variantId int
+
+	if err := conn(ctx, v.DB).QueryRow(ctx, `INSERT INTO product_variant (product_id, size, color, sku, stock) VALUES ($1, $2, $3, $4, $5) RETURNING id`, productId, data.Size, data.Color, data.SKU, data.Stock).Scan(&variantId); err != nil {
+		return 0, err
+	}
+
+	return variantId, nil
+}
+func (v *VariantPgStorage) Update(ctx context.Context, productId, id int, data *types.UpdateVariant) error {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.Update")
+	defer cancel()
+
+	if _, err := conn(ctx, v.DB).Exec(ctx, `UPDATE product_variant v SET
+                     sku=COALESCE(NULLIF($1,''), v.sku),
+                     stock=COALESCE($2, v.stock) WHERE id = $3 AND product_id = $4`, data.SKU, data.Stock, id, productId); err != nil {
+		return err
+	}
+
+	return nil
+}
+func (v *VariantPgStorage) Delete(ctx context.Context, productId, id int) error {
+	ctx, cancel := v.Timeouts.apply(ctx, "VariantStorage.Delete")
+	defer cancel()
+
+	if _, err := conn(ctx, v.DB).Exec(ctx, `DELETE FROM product_variant WHERE id = $1 AND product_id = $2`, id, productId); err != nil {
+		return err
+	}
+	return nil
+}
@@ -2,38 +2,53 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
+	"og-style/apperrors"
+	"og-style/config"
 	"og-style/models"
 	"og-style/processors"
 	"og-style/types"
 	"og-style/utils"
-	"os"
+	"strconv"
 	"time"
 	"unicode/utf8"
 )
 
+var (
+	errUnauthorized            = apperrors.Unauthorized("unauthorized", "необходимо войти в аккаунт")
+	errFieldsRequired          = apperrors.Validation("fields_required", "все поля обязательны")
+	errEmailRequired           = apperrors.Validation("email_required", "укажите эл.почту")
+	errPasswordTooShort        = apperrors.Validation("password_too_short", "пароль должен содержать не менее 8 символов")
+	errInvalidResetLink        = apperrors.Validation("invalid_reset_link", "ссылка для восстановления пароля недействительна или истекла")
+	errInvalidVerificationLink = apperrors.Validation("invalid_verification_link", "ссылка для подтверждения эл.почты недействительна или истекла")
+)
+
 type AuthHandler struct {
 	AuthProcessor processors.AuthProcessor
+	Config        config.AuthConfig
+	Proxies       utils.TrustedProxies
 }
 
 func (a *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var data types.CreateUser
 
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	errors := utils.ValidateStruct(data)
+	errors := utils.ValidateStruct(data, utils.Locale(r))
 	if errors != nil {
-		utils.SendValidatonErrors(w, errors)
+		utils.SendValidatonErrors(w, r, errors)
 		return
 	}
 
-	if err := a.AuthProcessor.SignUp(data); err != nil {
-		utils.BadRequestError(w, err)
+	if data.Locale == "" {
+		data.Locale = utils.Locale(r)
+	}
+
+	if err := a.AuthProcessor.SignUp(r.Context(), data); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -44,19 +59,18 @@ func (a *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	var body = make(map[string]string)
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		utils.SendError(w, err, http.StatusBadRequest)
+		utils.SendError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	if body["email"] == "" || body["password"] == "" {
-		utils.SendError(w, errors.New("all fields are required"), http.StatusBadRequest)
+		utils.SendAppError(w, r, errFieldsRequired)
 		return
 	}
 
-	data, err := a.AuthProcessor.SignIn(body["email"], body["password"])
+	data, err := a.AuthProcessor.SignIn(r.Context(), body["email"], body["password"], r.UserAgent(), a.Proxies.ClientIP(r))
 	if err != nil {
-		fmt.Println(err)
-		utils.SendError(w, err, http.StatusBadRequest)
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -66,12 +80,12 @@ func (a *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 func (a *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := r.Cookie("refreshToken")
 	if err != nil {
-		utils.UnauthorizedError(w, errors.New("unauthorized"))
+		utils.SendAppError(w, r, errUnauthorized)
 		return
 	}
 
-	if data, err := a.AuthProcessor.RefreshTokens(refreshToken.Value); err != nil {
-		utils.ForbiddenError(w, err)
+	if data, err := a.AuthProcessor.RefreshTokens(r.Context(), refreshToken.Value); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	} else {
 		a.attachTokensToCookie(w, data.AccessToken, data.RefreshToken)
@@ -84,22 +98,22 @@ func (a *AuthHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	body := make(map[string]string, 1)
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	if body["password"] == "" || body["oldPassword"] == "" {
-		utils.BadRequestError(w, errors.New("all fields are required"))
+		utils.SendAppError(w, r, errFieldsRequired)
 		return
 	}
 
 	if utf8.RuneCountInString(body["password"]) < 8 {
-		utils.BadRequestError(w, errors.New("password must be more or equal 8 characters"))
+		utils.SendAppError(w, r, errPasswordTooShort)
 		return
 	}
 
-	if err := a.AuthProcessor.UpdatePassword(user.ID, body["oldPassword"], body["password"]); err != nil {
-		utils.BadRequestError(w, err)
+	if err := a.AuthProcessor.UpdatePassword(r.Context(), user.ID, body["oldPassword"], body["password"]); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -109,18 +123,18 @@ func (a *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	body := make(map[string]string, 1)
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	if body["email"] == "" {
-		utils.BadRequestError(w, errors.New("provide email address"))
+		utils.SendAppError(w, r, errEmailRequired)
 		return
 	}
 
-	err := a.AuthProcessor.ForgotPassword(body["email"])
+	err := a.AuthProcessor.ForgotPassword(r.Context(), body["email"])
 	if err != nil {
-		utils.BadRequestError(w, err)
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -128,33 +142,120 @@ func (a *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 
 }
 func (a *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
-	email := r.URL.Query().Get("email")
+	token := r.URL.Query().Get("token")
 	m := make(map[string]string, 1)
 
 	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	if m["password"] == "" || utf8.RuneCountInString(m["password"]) < 8 {
-		utils.BadRequestError(w, errors.New("password must be more than 8 symbols"))
+		utils.SendAppError(w, r, errPasswordTooShort)
+		return
+	}
+
+	if token == "" {
+		utils.SendAppError(w, r, errInvalidResetLink)
+		return
+	}
+
+	if err := a.AuthProcessor.ResetPassword(r.Context(), token, m["password"]); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (a *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if refreshToken, err := r.Cookie("refreshToken"); err == nil {
+		if err := a.AuthProcessor.Logout(r.Context(), refreshToken.Value); err != nil {
+			utils.SendAppError(w, r, err)
+			return
+		}
+	}
+
+	a.clearTokenCookies(w)
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (a *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if err := a.AuthProcessor.LogoutAll(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	a.clearTokenCookies(w)
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (a *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	var refreshToken string
+	if cookie, err := r.Cookie("refreshToken"); err == nil {
+		refreshToken = cookie.Value
+	}
+
+	if sessions, err := a.AuthProcessor.GetSessions(r.Context(), user.ID, refreshToken); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, sessions, http.StatusOK)
+	}
+}
+func (a *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if err := a.AuthProcessor.ResetPassword(email, m["password"]); err != nil {
-		utils.BadRequestError(w, err)
+	if err := a.AuthProcessor.DeleteSession(r.Context(), user.ID, id); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
 	utils.SendJSON(w, "success", http.StatusOK)
 }
+func (a *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	body := make(map[string]string, 1)
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if body["token"] == "" {
+		utils.SendAppError(w, r, errInvalidVerificationLink)
+		return
+	}
+
+	if err := a.AuthProcessor.VerifyEmail(r.Context(), body["token"]); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (a *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if err := a.AuthProcessor.ResendVerification(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "Check your email to verify your address", http.StatusOK)
+}
 func (a *AuthHandler) attachTokensToCookie(w http.ResponseWriter, accessToken, refreshToken string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "accessToken",
 		Value:    accessToken,
 		Path:     "/",
-		Expires:  time.Now().Add(time.Minute * 30),
-		Secure:   os.Getenv("GO_ENV") == "production",
+		Expires:  time.Now().Add(a.Config.AccessTokenTTL),
+		Secure:   a.Config.SecureCookies,
 		HttpOnly: true,
 	})
 
@@ -162,8 +263,21 @@ func (a *AuthHandler) attachTokensToCookie(w http.ResponseWriter, accessToken, r
 		Name:     "refreshToken",
 		Value:    refreshToken,
 		Path:     "/",
-		Expires:  time.Now().Add(time.Hour * 24 * 30),
-		Secure:   os.Getenv("GO_ENV") == "production",
+		Expires:  time.Now().Add(a.Config.RefreshTokenTTL),
+		Secure:   a.Config.SecureCookies,
 		HttpOnly: true,
 	})
 }
+func (a *AuthHandler) clearTokenCookies(w http.ResponseWriter) {
+	for _, name := range []string{"accessToken", "refreshToken"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Expires:  time.Unix(0, 0),
+			Secure:   a.Config.SecureCookies,
+			HttpOnly: true,
+		})
+	}
+}
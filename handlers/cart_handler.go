@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"og-style/models"
+	"og-style/processors"
+	"og-style/types"
+	"og-style/utils"
+	"strconv"
+)
+
+type CartHandler struct {
+	CartProcessor processors.CartProcessor
+}
+
+func (c *CartHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if cart, err := c.CartProcessor.Get(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, cart, http.StatusOK)
+	}
+}
+func (c *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+	var body types.AddCartItem
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if errors := utils.ValidateStruct(body, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
+		return
+	}
+
+	if err := c.CartProcessor.AddItem(r.Context(), user.ID, &body); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusCreated)
+}
+func (c *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	var body types.UpdateCartItem
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if errors := utils.ValidateStruct(body, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
+		return
+	}
+
+	if err := c.CartProcessor.UpdateItem(r.Context(), user.ID, id, &body); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (c *CartHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if err := c.CartProcessor.DeleteItem(r.Context(), user.ID, id); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (c *CartHandler) Clear(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if err := c.CartProcessor.Clear(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"og-style/services"
+	"og-style/utils"
+	"sync/atomic"
+)
+
+type HealthHandler struct {
+	// Ready is false until the server is up and again once it starts draining.
+	Ready  *atomic.Bool
+	Health *services.HealthService
+}
+
+// Liveness reports that the process is up and serving; it checks no dependencies.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	utils.SendJSON(w, "ok", http.StatusOK)
+}
+
+// Readiness reports whether the server should receive traffic, with the
+// status of every dependency.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.Ready.Load() {
+		utils.SendJSON(w, services.ReadinessReport{Ready: false, Dependencies: map[string]services.DependencyStatus{}}, http.StatusServiceUnavailable)
+		return
+	}
+
+	report := h.Health.Check()
+	if !report.Ready {
+		utils.SendJSON(w, report, http.StatusServiceUnavailable)
+		return
+	}
+
+	utils.SendJSON(w, report, http.StatusOK)
+}
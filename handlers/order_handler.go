@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"og-style/models"
+	"og-style/processors"
+	"og-style/types"
+	"og-style/utils"
+	"strconv"
+)
+
+type OrderHandler struct {
+	OrderProcessor processors.OrderProcessor
+}
+
+func (o *OrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if order, err := o.OrderProcessor.Create(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, order, http.StatusCreated)
+	}
+}
+func (o *OrderHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	if orders, err := o.OrderProcessor.GetAll(r.Context(), user.ID); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, orders, http.StatusOK)
+	}
+}
+func (o *OrderHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if order, err := o.OrderProcessor.Get(r.Context(), user.ID, id); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, order, http.StatusOK)
+	}
+}
+func (o *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	var body types.UpdateOrderStatus
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if errors := utils.ValidateStruct(body, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
+		return
+	}
+
+	if err := o.OrderProcessor.UpdateStatus(r.Context(), id, models.OrderStatus(body.Status)); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"og-style/models"
+	"og-style/processors"
+	"og-style/services"
+	"og-style/utils"
+	"strconv"
+)
+
+const maxWebhookSize = 64 * 1024
+
+type PaymentHandler struct {
+	PaymentProcessor processors.PaymentProcessor
+}
+
+func (p *PaymentHandler) CreateIntent(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user").(*models.User)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if intent, err := p.PaymentProcessor.CreateIntent(r.Context(), user.ID, id); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, intent, http.StatusCreated)
+	}
+}
+func (p *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if err := p.PaymentProcessor.Refund(r.Context(), id); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (p *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if err := p.PaymentProcessor.HandleWebhook(r.Context(), payload, r.Header.Get(services.PaymentSignatureHeader)); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
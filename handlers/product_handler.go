@@ -27,12 +27,12 @@ func (p *ProductHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if product, err := p.ProductProcessor.Get(id); err != nil {
-		utils.BadRequestError(w, err)
+	if product, err := p.ProductProcessor.Get(r.Context(), id); err != nil {
+		utils.SendAppError(w, r, err)
 	} else {
 		utils.SendJSON(w, product, http.StatusOK)
 	}
@@ -42,24 +42,24 @@ func (p *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 	m, err := p.transformUrlParams(r.URL.Query())
 	if err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	var getProductsParams types.GetProductsParams
 
 	if err := mapstructure.Decode(m, &getProductsParams); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if errors := utils.ValidateStruct(getProductsParams); errors != nil {
-		utils.SendValidatonErrors(w, errors)
+	if errors := utils.ValidateStruct(getProductsParams, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
 		return
 	}
 
-	if products, err := p.ProductProcessor.GetAll(getProductsParams); err != nil {
-		utils.BadRequestError(w, err)
+	if products, err := p.ProductProcessor.GetAll(r.Context(), getProductsParams); err != nil {
+		utils.SendAppError(w, r, err)
 	} else {
 		utils.SendJSON(w, products, http.StatusOK)
 	}
@@ -70,17 +70,17 @@ func (p *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var body types.CreateProduct
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if err := utils.ValidateStruct(body); err != nil {
-		utils.SendValidatonErrors(w, err)
+	if err := utils.ValidateStruct(body, utils.Locale(r)); err != nil {
+		utils.SendValidatonErrors(w, r, err)
 		return
 	}
 
-	if err := p.ProductProcessor.Create(&body); err != nil {
-		utils.BadRequestError(w, err)
+	if err := p.ProductProcessor.Create(r.Context(), &body); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -89,18 +89,18 @@ func (p *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (p *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	var updateProduct types.UpdateProduct
 	if err := json.NewDecoder(r.Body).Decode(&updateProduct); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if err := p.ProductProcessor.Update(id, &updateProduct); err != nil {
-		utils.BadRequestError(w, err)
+	if err := p.ProductProcessor.Update(r.Context(), id, &updateProduct); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -109,12 +109,12 @@ func (p *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 func (p *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
-	if err := p.ProductProcessor.Delete(id); err != nil {
-		utils.BadRequestError(w, err)
+	if err := p.ProductProcessor.Delete(r.Context(), id); err != nil {
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -123,12 +123,12 @@ func (p *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 func (p *ProductHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseMultipartForm(maxFileSize); err != nil {
-		utils.BadRequestError(w, err)
+		utils.BadRequestError(w, r, err)
 		return
 	}
 
 	if len(r.MultipartForm.File[imageFieldName]) != 4 {
-		utils.BadRequestError(w, errors.New("требуемое количество картинок-4"))
+		utils.BadRequestError(w, r, errors.New("требуемое количество картинок-4"))
 		return
 	}
 
@@ -153,9 +153,9 @@ func (p *ProductHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 			}
 			defer file.Close()
 
-			imgUrl, uploadErr := p.ProductProcessor.UploadImage(file)
+			imgUrl, uploadErr := p.ProductProcessor.UploadImage(r.Context(), file)
 			if uploadErr != nil {
-				addErr(err)
+				addErr(uploadErr)
 				return
 			}
 
@@ -166,7 +166,7 @@ func (p *ProductHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	wg.Wait()
 
 	if err != nil {
-		utils.InternalServerError(w, errors.New("что-то пошло не так"))
+		utils.SendAppError(w, r, err)
 		return
 	}
 
@@ -176,13 +176,14 @@ func (p *ProductHandler) GetFilters(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
 
 	if category == "" || (category != "одежда" && category != "обувь") {
-		utils.BadRequestError(w, errors.New("категория должно быть один из вариантов одежда,обувь"))
+		utils.BadRequestError(w, r, errors.New("категория должно быть один из вариантов одежда,обувь"))
 		return
 	}
 
-	if filters, err := p.ProductProcessor.GetFilters(category); err != nil {
-		fmt.Println(err)
-		utils.InternalServerError(w, errors.New("Что-то пошло не так.Повторите попытку чуть позже"))
+	inStock, _ := strconv.ParseBool(r.URL.Query().Get("inStock"))
+
+	if filters, err := p.ProductProcessor.GetFilters(r.Context(), category, inStock); err != nil {
+		utils.SendAppError(w, r, err)
 	} else {
 		utils.SendJSON(w, filters, http.StatusOK)
 	}
@@ -199,6 +200,12 @@ func (p *ProductHandler) transformUrlParams(params map[string][]string) (*map[st
 				return nil, fmt.Errorf("%s должно быть целым числом", key)
 			}
 			m[key] = num
+		case "inStock":
+			inStock, err := strconv.ParseBool(val[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s должно быть логическим значением", key)
+			}
+			m[key] = inStock
 		case "colors", "size":
 			m[key] = strings.Split(strings.Join(val, ","), ",")
 		case "brand":
@@ -207,7 +214,6 @@ func (p *ProductHandler) transformUrlParams(params map[string][]string) (*map[st
 			for _, value := range splitedArr {
 				num, err := strconv.Atoi(value)
 				if err != nil {
-					fmt.Println(err)
 					return nil, fmt.Errorf("%s должно быть массив целых чисел", key)
 				}
 				slice = append(slice, num)
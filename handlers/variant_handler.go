@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"og-style/processors"
+	"og-style/types"
+	"og-style/utils"
+	"strconv"
+)
+
+type VariantHandler struct {
+	VariantProcessor processors.VariantProcessor
+}
+
+func (v *VariantHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	productId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if variants, err := v.VariantProcessor.GetAll(r.Context(), productId); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, variants, http.StatusOK)
+	}
+}
+func (v *VariantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	productId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	var body types.CreateVariant
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if errors := utils.ValidateStruct(body, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
+		return
+	}
+
+	if variant, err := v.VariantProcessor.Create(r.Context(), productId, &body); err != nil {
+		utils.SendAppError(w, r, err)
+	} else {
+		utils.SendJSON(w, variant, http.StatusCreated)
+	}
+}
+func (v *VariantHandler) Update(w http.ResponseWriter, r *http.Request) {
+	productId, id, err := v.parseIds(r)
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	var body types.UpdateVariant
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if errors := utils.ValidateStruct(body, utils.Locale(r)); errors != nil {
+		utils.SendValidatonErrors(w, r, errors)
+		return
+	}
+
+	if err := v.VariantProcessor.Update(r.Context(), productId, id, &body); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (v *VariantHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productId, id, err := v.parseIds(r)
+	if err != nil {
+		utils.BadRequestError(w, r, err)
+		return
+	}
+
+	if err := v.VariantProcessor.Delete(r.Context(), productId, id); err != nil {
+		utils.SendAppError(w, r, err)
+		return
+	}
+
+	utils.SendJSON(w, "success", http.StatusOK)
+}
+func (v *VariantHandler) parseIds(r *http.Request) (int, int, error) {
+	productId, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	id, err := strconv.Atoi(r.PathValue("variantId"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return productId, id, nil
+}
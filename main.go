@@ -4,86 +4,331 @@ import (
 	"context"
 	cloudinary2 "github.com/cloudinary/cloudinary-go/v2"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"og-style/config"
 	"og-style/db"
 	"og-style/handlers"
+	"og-style/metrics"
 	"og-style/middlewares"
 	"og-style/processors"
 	"og-style/services"
+	"og-style/tracing"
+	"og-style/utils"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 func main() {
-	if err := godotenv.Load(".env"); err != nil {
-		log.Fatal("Something went wrong")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	pool, err := pgxpool.New(context.Background(), os.Getenv("DB_CONNECTION"))
+	cfg, err := config.Load("")
 	if err != nil {
-		log.Fatal("Error when trying to connect to database")
+		log.Fatal(err)
 	}
-	defer pool.Close()
 
-	cloudinary, cldErr := cloudinary2.NewFromURL(os.Getenv("CLOUDINARY_URL"))
+	logger := newLogger(cfg.Log)
+	slog.SetDefault(logger)
+
+	trustedProxies, err := utils.ParseTrustedProxies(cfg.HTTP.TrustedProxies)
+	if err != nil {
+		logger.Error("Error when trying to parse the trusted proxies", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.Error("Error when trying to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.Database.URL)
+	if err != nil {
+		logger.Error("Error when trying to parse the database URL", "error", err)
+		os.Exit(1)
+	}
+	poolConfig.ConnConfig.Tracer = db.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		logger.Error("Error when trying to connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	cloudinary, cldErr := cloudinary2.NewFromURL(cfg.Cloudinary.URL)
 	if cldErr != nil {
-		log.Fatal("Error when trying to connect to cloudinary")
+		logger.Error("Error when trying to connect to cloudinary", "error", cldErr)
+		os.Exit(1)
+	}
+
+	var mailer services.Mailer
+	switch cfg.Mail.Backend {
+	case "file":
+		mailer = &services.FileMailer{Dir: cfg.Mail.Dir, From: cfg.Mail.From}
+	case "memory":
+		mailer = &services.MemoryMailer{}
+	default:
+		mailer = &services.SMTPMailer{
+			Host:               cfg.Mail.SMTP.Host,
+			Port:               cfg.Mail.SMTP.Port,
+			Username:           cfg.Mail.SMTP.Username,
+			Password:           cfg.Mail.SMTP.Password,
+			From:               cfg.Mail.From,
+			ImplicitTLS:        cfg.Mail.SMTP.TLS == "implicit",
+			InsecureSkipVerify: cfg.Mail.SMTP.InsecureSkipVerify,
+		}
+	}
+
+	emailRenderer, err := services.NewTemplateEmailRenderer(cfg.Mail.TemplatesDir, cfg.Mail.FrontendURL)
+	if err != nil {
+		logger.Error("Error when trying to parse email templates", "error", err)
+		os.Exit(1)
 	}
 
 	mux := http.NewServeMux()
 	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"http://localhost:5173"},
+		AllowedOrigins: cfg.HTTP.CORSOrigins,
 		AllowedMethods: []string{"GET", "POST", "DELETE", "PATCH", "PUT"},
 		//AllowedHeaders:             nil,
 		//ExposedHeaders:             nil,
 		AllowCredentials: true,
-		Debug:            true,
+		Debug:            cfg.HTTP.CORSDebug,
 	})
-	handler := c.Handler(mux)
+	handler := middlewares.Tracing(middlewares.Logging(middlewares.Metrics(middlewares.SpanRoute(c.Handler(mux))), logger))
+
+	var ready atomic.Bool
 
 	var (
 		imgUploaderProcessor = services.CldImageUploaderService{Cloudinary: cloudinary}
+		queryTimeouts        = db.QueryTimeouts{Default: cfg.Database.QueryTimeout, Operations: cfg.Database.QueryTimeouts}
+		txManager            = db.PgTxManager{DB: pool, MaxRetries: cfg.Database.TxRetries}
+
+		userStorage        = db.UserPgStorage{DB: pool, Timeouts: queryTimeouts}
+		cartStorage        = db.CartPgStorage{DB: pool, Timeouts: queryTimeouts}
+		tokenStorage       = db.TokenPgStorage{DB: pool, Timeouts: queryTimeouts}
+		productStorage     = db.ProductPgStorage{DB: pool, PageSize: cfg.Products.PageSize, Timeouts: queryTimeouts}
+		orderStorage       = db.OrderPgStorage{DB: pool, Timeouts: queryTimeouts}
+		variantStorage     = db.VariantPgStorage{DB: pool, Timeouts: queryTimeouts}
+		reservationStorage = db.ReservationPgStorage{DB: pool, Timeouts: queryTimeouts}
+		paymentStorage     = db.PaymentPgStorage{DB: pool, Timeouts: queryTimeouts}
+		outboxStorage      = db.OutboxPgStorage{DB: pool, Timeouts: queryTimeouts}
 
-		userStorage    = db.UserPgStorage{DB: pool}
-		cartStorage    = db.CartPgStorage{DB: pool}
-		tokenStorage   = db.TokenPgStorage{DB: pool}
-		productStorage = db.ProductPgStorage{DB: pool}
+		inventoryService = services.PgInventoryService{ReservationStorage: &reservationStorage, TTL: cfg.Inventory.ReservationTTL}
+		outboxWorker     = services.OutboxWorker{
+			Storage:      &outboxStorage,
+			Mailer:       mailer,
+			Workers:      cfg.Mail.Outbox.Workers,
+			BatchSize:    cfg.Mail.Outbox.BatchSize,
+			PollInterval: cfg.Mail.Outbox.PollInterval,
+			MaxAttempts:  cfg.Mail.Outbox.MaxAttempts,
+			BaseBackoff:  cfg.Mail.Outbox.BaseBackoff,
+			MaxBackoff:   cfg.Mail.Outbox.MaxBackoff,
+		}
+		healthService = services.HealthService{
+			CacheTTL: cfg.Health.CacheTTL,
+			Checks: []services.HealthCheck{
+				{Name: "database", Checker: services.HealthCheckFunc(pool.Ping), Timeout: cfg.Health.DatabaseTimeout},
+				{Name: "imageStorage", Checker: &imgUploaderProcessor, Timeout: cfg.Health.ImageStorageTimeout, CacheTTL: cfg.Health.ImageStorageCacheTTL},
+				{Name: "mailer", Checker: mailer, Timeout: cfg.Health.MailerTimeout},
+			},
+		}
+		paymentProvider = services.FakePaymentProvider{
+			Secret:      []byte(cfg.Payments.WebhookSecret),
+			WebhookURL:  cfg.Payments.WebhookURL,
+			AutoConfirm: cfg.Payments.AutoConfirm,
+		}
 
-		authProcessor    = processors.AuthPgProcessor{&userStorage, &cartStorage, &tokenStorage}
-		productProcessor = processors.ProductPgProcessor{&productStorage, &imgUploaderProcessor}
+		authProcessor    = processors.AuthPgProcessor{UserStorage: &userStorage, CartStorage: &cartStorage, TokenStorage: &tokenStorage, Tx: &txManager, Emails: emailRenderer, Config: cfg.Auth}
+		productProcessor = processors.ProductPgProcessor{ProductStorage: &productStorage, ImageUploader: &imgUploaderProcessor}
+		cartProcessor    = processors.CartPgProcessor{CartStorage: &cartStorage, ProductStorage: &productStorage, VariantStorage: &variantStorage, Inventory: &inventoryService, Tx: &txManager}
+		orderProcessor   = processors.OrderPgProcessor{OrderStorage: &orderStorage, CartStorage: &cartStorage, UserStorage: &userStorage, OutboxStorage: &outboxStorage, Tx: &txManager, Emails: emailRenderer}
+		variantProcessor = processors.VariantPgProcessor{VariantStorage: &variantStorage, ProductStorage: &productStorage}
+		paymentProcessor = processors.PaymentPgProcessor{PaymentStorage: &paymentStorage, OrderStorage: &orderStorage, Provider: &paymentProvider, Tx: &txManager}
 
-		authHandler    = handlers.AuthHandler{AuthProcessor: &authProcessor}
+		authHandler    = handlers.AuthHandler{AuthProcessor: &authProcessor, Config: cfg.Auth, Proxies: trustedProxies}
 		productHandler = handlers.ProductHandler{ProductProcessor: &productProcessor}
+		cartHandler    = handlers.CartHandler{CartProcessor: &cartProcessor}
+		orderHandler   = handlers.OrderHandler{OrderProcessor: &orderProcessor}
+		variantHandler = handlers.VariantHandler{VariantProcessor: &variantProcessor}
+		paymentHandler = handlers.PaymentHandler{PaymentProcessor: &paymentProcessor}
+		healthHandler  = handlers.HealthHandler{Ready: &ready, Health: &healthService}
 	)
 
+	jobsCtx, stopJobs := context.WithCancel(utils.WithLogger(context.Background(), logger))
+	jobs := sync.WaitGroup{}
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		inventoryService.RunSweeper(utils.WithLogger(jobsCtx, logger.With("job", "reservation-sweeper")), cfg.Inventory.SweepInterval)
+	}()
+	go func() {
+		defer jobs.Done()
+		outboxWorker.Run(utils.WithLogger(jobsCtx, logger.With("job", "outbox-worker")))
+	}()
+
+	prometheus.MustRegister(&metrics.PoolCollector{Pool: pool})
+	mux.HandleFunc("GET /healthz", healthHandler.Liveness)
+	mux.HandleFunc("GET /readyz", healthHandler.Readiness)
+
 	mux.HandleFunc("POST /api/v1/auth/sign-up", authHandler.SignUp)
 	mux.HandleFunc("POST /api/v1/auth/sign-in", authHandler.SignIn)
 	mux.HandleFunc("POST /api/v1/auth/refresh-tokens", authHandler.RefreshTokens)
 	mux.HandleFunc("POST /api/v1/auth/forgot-password", authHandler.ForgotPassword)
 	mux.HandleFunc("PATCH /api/v1/auth/reset-password", authHandler.ResetPassword)
-	mux.HandleFunc("PATCH /api/v1/auth/update-password", middlewares.Auth(authHandler.UpdatePassword, &userStorage))
+	mux.HandleFunc("PATCH /api/v1/auth/update-password", middlewares.Auth(authHandler.UpdatePassword, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/auth/logout", authHandler.Logout)
+	mux.HandleFunc("POST /api/v1/auth/logout-all", middlewares.Auth(authHandler.LogoutAll, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/auth/verify-email", authHandler.VerifyEmail)
+	mux.HandleFunc("POST /api/v1/auth/resend-verification", middlewares.Auth(authHandler.ResendVerification, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("GET /api/v1/auth/sessions", middlewares.Auth(authHandler.GetSessions, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("DELETE /api/v1/auth/sessions/{id}", middlewares.Auth(authHandler.DeleteSession, &userStorage, cfg.Auth.JWTSecret))
 
 	mux.HandleFunc("/api/v1/products", productHandler.GetAll)
 	mux.HandleFunc("/api/v1/products/{id}", productHandler.Get)
 	mux.HandleFunc("GET /api/v1/products/filters", productHandler.GetFilters)
-	mux.HandleFunc("POST /api/v1/products", middlewares.Auth(middlewares.RestrictTo(productHandler.Create, "admin"), &userStorage))
-	mux.HandleFunc("PATCH /api/v1/products/{id}", middlewares.Auth(middlewares.RestrictTo(productHandler.Update, "admin"), &userStorage))
-	mux.HandleFunc("DELETE /api/v1/products/{id}", middlewares.Auth(middlewares.RestrictTo(productHandler.Delete, "admin"), &userStorage))
-	mux.HandleFunc("POST /api/v1/products/upload-image", middlewares.Auth(middlewares.RestrictTo(productHandler.UploadImage, "admin"), &userStorage))
+	mux.HandleFunc("POST /api/v1/products", middlewares.Auth(middlewares.RestrictTo(productHandler.Create, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("PATCH /api/v1/products/{id}", middlewares.Auth(middlewares.RestrictTo(productHandler.Update, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("DELETE /api/v1/products/{id}", middlewares.Auth(middlewares.RestrictTo(productHandler.Delete, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/products/upload-image", middlewares.Auth(middlewares.RestrictTo(productHandler.UploadImage, "admin"), &userStorage, cfg.Auth.JWTSecret))
+
+	mux.HandleFunc("GET /api/v1/products/{id}/variants", variantHandler.GetAll)
+	mux.HandleFunc("POST /api/v1/products/{id}/variants", middlewares.Auth(middlewares.RestrictTo(variantHandler.Create, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("PATCH /api/v1/products/{id}/variants/{variantId}", middlewares.Auth(middlewares.RestrictTo(variantHandler.Update, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("DELETE /api/v1/products/{id}/variants/{variantId}", middlewares.Auth(middlewares.RestrictTo(variantHandler.Delete, "admin"), &userStorage, cfg.Auth.JWTSecret))
+
+	mux.HandleFunc("GET /api/v1/cart", middlewares.Auth(cartHandler.Get, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/cart", middlewares.Auth(cartHandler.AddItem, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("DELETE /api/v1/cart", middlewares.Auth(cartHandler.Clear, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("PATCH /api/v1/cart/{id}", middlewares.Auth(cartHandler.UpdateItem, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("DELETE /api/v1/cart/{id}", middlewares.Auth(cartHandler.DeleteItem, &userStorage, cfg.Auth.JWTSecret))
+
+	mux.HandleFunc("POST /api/v1/orders", middlewares.Auth(middlewares.Verified(orderHandler.Create), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("GET /api/v1/orders", middlewares.Auth(orderHandler.GetAll, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("GET /api/v1/orders/{id}", middlewares.Auth(orderHandler.Get, &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("PATCH /api/v1/orders/{id}/status", middlewares.Auth(middlewares.RestrictTo(orderHandler.UpdateStatus, "admin"), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/orders/{id}/pay", middlewares.Auth(middlewares.Verified(paymentHandler.CreateIntent), &userStorage, cfg.Auth.JWTSecret))
+	mux.HandleFunc("POST /api/v1/orders/{id}/refund", middlewares.Auth(middlewares.RestrictTo(paymentHandler.Refund, "admin"), &userStorage, cfg.Auth.JWTSecret))
+
+	mux.HandleFunc("POST /api/v1/payments/webhook", paymentHandler.Webhook)
 
 	server := http.Server{
-		Addr:        ":4000",
+		Addr:        cfg.HTTP.Addr,
 		Handler:     handler,
-		ReadTimeout: time.Second * 4,
+		ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ReadTimeout: cfg.HTTP.ReadTimeout,
 		//WriteTimeout: time.Second * 5,
-		IdleTimeout: time.Second * 120,
+		IdleTimeout: cfg.HTTP.IdleTimeout,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatal("Something went wrong")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Metrics are served on their own listener, which is meant to stay
+	// internal, rather than next to the public API.
+	adminMux := http.NewServeMux()
+	adminMux.Handle("GET /metrics", promhttp.Handler())
+
+	adminServer := http.Server{
+		Addr:        cfg.HTTP.AdminAddr,
+		Handler:     adminMux,
+		ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
+		ReadTimeout: cfg.HTTP.ReadTimeout,
+		IdleTimeout: cfg.HTTP.IdleTimeout,
+	}
+
+	// Bind before reporting ready so /readyz never says yes for a port we
+	// could not take; a failed bind or serve still tears everything down and
+	// then exits non-zero.
+	serverErr := make(chan error, 2)
+	serve := func(server *http.Server, name string) bool {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			serverErr <- err
+			return false
+		}
+
+		go func() {
+			serverErr <- server.Serve(listener)
+		}()
+		logger.Info("listening", "server", name, "addr", listener.Addr().String())
+		return true
+	}
+
+	if serve(&adminServer, "admin") && serve(&server, "api") {
+		ready.Store(true)
+	}
+
+	var failed error
+	select {
+	case failed = <-serverErr:
+		logger.Error("server stopped", "error", failed)
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	}
+	stop()
+
+	// Teardown order: stop taking traffic, drain in-flight requests, stop the
+	// background jobs (the outbox worker finishes the emails it holds), and
+	// only then close the pool they all use.
+	ready.Store(false)
+	time.Sleep(cfg.HTTP.ShutdownDelay)
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.HTTP.DrainTimeout)
+	defer cancelDrain()
+
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Error("drain http server", "error", err)
 	}
 
+	if err := adminServer.Shutdown(drainCtx); err != nil {
+		logger.Error("drain admin server", "error", err)
+	}
+
+	stopJobs()
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(jobsDone)
+	}()
+
+	select {
+	case <-jobsDone:
+	case <-drainCtx.Done():
+		logger.Warn("background jobs did not stop before the drain timeout")
+	}
+
+	pool.Close()
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error("flush traces", "error", err)
+	}
+	logger.Info("shutdown complete")
+
+	if failed != nil {
+		os.Exit(1)
+	}
+}
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.Level))
+
+	options := &slog.HandlerOptions{Level: level}
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(os.Stdout, options))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, options))
 }
@@ -0,0 +1,61 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "ogstyle"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ImageUploadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "image_upload_duration_seconds",
+		Help:      "Duration of image uploads to the image storage, successful or not.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16},
+	})
+
+	ImageUploadFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_upload_failures_total",
+		Help:      "Image uploads that failed.",
+	})
+
+	EmailsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_total",
+		Help:      "Outbox email delivery attempts by outcome: sent, retry or dead.",
+	}, []string{"outcome"})
+
+	SignUps = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_ups_total",
+		Help:      "Accounts created.",
+	})
+
+	SignIns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_ins_total",
+		Help:      "Sign-in attempts by result: success or failure.",
+	}, []string{"result"})
+
+	OrdersPlaced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_placed_total",
+		Help:      "Orders placed.",
+	})
+)
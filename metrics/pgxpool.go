@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector exports the statistics of a pgxpool.Pool, read on every scrape.
+type PoolCollector struct {
+	Pool *pgxpool.Pool
+}
+
+var (
+	poolAcquiredConns = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns", "Connections currently in use.", nil, nil)
+	poolIdleConns     = prometheus.NewDesc(namespace+"_pgxpool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotalConns    = prometheus.NewDesc(namespace+"_pgxpool_total_conns", "All connections in the pool, including ones being constructed.", nil, nil)
+	poolMaxConns      = prometheus.NewDesc(namespace+"_pgxpool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquires      = prometheus.NewDesc(namespace+"_pgxpool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(namespace+"_pgxpool_empty_acquires_total", "Acquires that had to wait because no connection was idle.", nil, nil)
+	poolCanceled      = prometheus.NewDesc(namespace+"_pgxpool_canceled_acquires_total", "Acquires canceled by their context.", nil, nil)
+	poolAcquireWait   = prometheus.NewDesc(namespace+"_pgxpool_acquire_duration_seconds_total", "Total time spent acquiring connections.", nil, nil)
+)
+
+func (p *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns, poolAcquires, poolEmptyAcquires, poolCanceled, poolAcquireWait} {
+		ch <- desc
+	}
+}
+func (p *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.Pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -2,36 +2,45 @@ package middlewares
 
 import (
 	"context"
-	"errors"
 	"net/http"
+	"og-style/apperrors"
 	"og-style/db"
 	"og-style/utils"
 )
 
-func Auth(handler http.HandlerFunc, userStorage db.UserStorage) http.HandlerFunc {
+var errUnauthorized = apperrors.Unauthorized("unauthorized", "необходимо войти в аккаунт")
+
+func Auth(handler http.HandlerFunc, userStorage db.UserStorage, jwtSecret string) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		accessToken, tokenErr := r.Cookie("accessToken")
 		if tokenErr != nil {
-			utils.UnauthorizedError(w, errors.New("unauthorized"))
+			utils.SendError(w, r, errUnauthorized, http.StatusUnauthorized)
 			return
 		}
 
-		claims, err := utils.ParseJWT(accessToken.Value)
+		claims, err := utils.ParseJWT(accessToken.Value, jwtSecret, utils.AccessToken)
 		if err != nil {
-			utils.UnauthorizedError(w, err)
+			utils.SendError(w, r, errUnauthorized.Wrap(err), http.StatusUnauthorized)
+			return
+		}
+
+		userId, ok := utils.ClaimInt(claims, "id")
+		if !ok {
+			utils.SendError(w, r, errUnauthorized, http.StatusUnauthorized)
 			return
 		}
 
-		if user, err := userStorage.Get(int(claims["id"].(float64))); err != nil {
-			utils.UnauthorizedError(w, err)
+		if user, err := userStorage.Get(r.Context(), userId); err != nil {
+			utils.SendAppError(w, r, err)
 			return
 		} else {
 			if user.ID == 0 {
-				utils.UnauthorizedError(w, err)
+				utils.SendError(w, r, errUnauthorized, http.StatusUnauthorized)
 				return
 			}
-			ctx := context.WithValue(context.Background(), "user", user)
+			setUserID(r.Context(), user.ID)
+			ctx := utils.WithLogger(context.WithValue(r.Context(), "user", user), utils.Logger(r.Context()).With("user_id", user.ID))
 			handler.ServeHTTP(w, r.WithContext(ctx))
 		}
 	}
@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"og-style/config"
+	"og-style/db"
+	"og-style/models"
+	"og-style/processors"
+	"og-style/utils"
+	"testing"
+	"time"
+)
+
+type fakeUserStorage struct {
+	db.UserStorage
+	user *models.User
+}
+
+func (f *fakeUserStorage) Get(ctx context.Context, id int) (*models.User, error) {
+	if id == f.user.ID {
+		return f.user, nil
+	}
+	return &models.User{}, nil
+}
+func (f *fakeUserStorage) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if email == f.user.Email {
+		return f.user, nil
+	}
+	return &models.User{}, nil
+}
+
+type fakeTokenStorage struct {
+	db.TokenStorage
+	sessions map[int]*models.Token
+}
+
+func (f *fakeTokenStorage) Get(ctx context.Context, id int) (*models.Token, error) {
+	if session, ok := f.sessions[id]; ok {
+		return session, nil
+	}
+	return &models.Token{}, nil
+}
+func (f *fakeTokenStorage) Create(ctx context.Context, userId int, userAgent, ip string) (int, error) {
+	id := len(f.sessions) + 1
+	f.sessions[id] = &models.Token{ID: id, UserID: userId, UserAgent: userAgent, IP: ip}
+	return id, nil
+}
+func (f *fakeTokenStorage) Rotate(ctx context.Context, id int, oldToken, newToken string) (bool, error) {
+	session, ok := f.sessions[id]
+	if !ok || session.RefreshToken != oldToken {
+		return false, nil
+	}
+	session.RefreshToken = newToken
+	return true, nil
+}
+func (f *fakeTokenStorage) DeleteAll(ctx context.Context, userId int) error {
+	for id, session := range f.sessions {
+		if session.UserID == userId {
+			delete(f.sessions, id)
+		}
+	}
+	return nil
+}
+
+func TestAuthRejectsRefreshTokenAfterLogoutAll(t *testing.T) {
+	const secret = "secret"
+	ctx := context.Background()
+
+	password, err := utils.HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUserStorage{user: &models.User{ID: 1, Email: "user@example.com", Password: password}}
+	auth := &processors.AuthPgProcessor{
+		UserStorage:  users,
+		TokenStorage: &fakeTokenStorage{sessions: map[int]*models.Token{}},
+		Config:       config.AuthConfig{JWTSecret: secret, AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour},
+	}
+
+	tokens, err := auth.SignIn(ctx, "user@example.com", "password", "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := auth.LogoutAll(ctx, users.user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := Auth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, users, secret)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	r.AddCookie(&http.Cookie{Name: "accessToken", Value: tokens.RefreshToken})
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh token used as an access token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if _, err := auth.RefreshTokens(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("refresh token still refreshes after LogoutAll")
+	}
+}
@@ -1,8 +1,8 @@
 package middlewares
 
 import (
-	"errors"
 	"net/http"
+	"og-style/apperrors"
 	"og-style/models"
 	"og-style/utils"
 )
@@ -28,6 +28,6 @@ func RestrictTo(next http.HandlerFunc, roles ...string) http.HandlerFunc {
 			}
 		}
 
-		utils.ForbiddenError(w, errors.New("у вас нет достука к этому маршруту"))
+		utils.SendError(w, r, apperrors.Forbidden("route_forbidden", "у вас нет достука к этому маршруту"), http.StatusForbidden)
 	}
 }
@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Tracing starts a server span for every request, continuing the trace from
+// the incoming traceparent header.
+func Tracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "HTTP request")
+}
+
+// SpanRoute renames the request span to the route pattern the mux matched.
+// It must wrap the mux without cloning the request in between, because the
+// mux stores the pattern on the request it is given.
+func SpanRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if r.Pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+		}
+	})
+}
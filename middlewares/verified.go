@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"net/http"
+	"og-style/apperrors"
+	"og-style/models"
+	"og-style/utils"
+)
+
+func Verified(next http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value("user").(*models.User)
+
+		if user.VerifiedAt == nil {
+			utils.SendError(w, r, apperrors.Forbidden("email_not_verified", "подтвердите эл.почту, чтобы продолжить"), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"og-style/config"
+	"og-style/db"
+	"og-style/internal/migratecmd"
+	"os"
+)
+
+// runMigrate implements the migrate subcommand. It only needs the database
+// configuration, so it can run before the rest of the environment exists.
+func runMigrate(args []string) error {
+	cfg, err := config.Load("", "Database")
+	if err != nil {
+		return err
+	}
+
+	pool, err := pgxpool.New(context.Background(), cfg.Database.URL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	return migratecmd.Run(context.Background(), &db.Migrator{DB: pool}, args, os.Stdout)
+}
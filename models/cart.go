@@ -4,3 +4,13 @@ type Cart struct {
 	ID     int `db:"id"`
 	UserID int `db:"user_id"`
 }
+
+type CartItem struct {
+	ID        int    `json:"id" db:"id"`
+	CartID    int    `json:"-" db:"cart_id"`
+	ProductID int    `json:"productId" db:"product_id"`
+	VariantID int    `json:"variantId" db:"variant_id"`
+	Size      string `json:"size" db:"size"`
+	Color     string `json:"color" db:"color"`
+	Quantity  int    `json:"quantity" db:"quantity"`
+}
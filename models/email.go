@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type Mail struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	HTML    string   `json:"html"`
+	Text    string   `json:"text"`
+}
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxDead    OutboxStatus = "dead"
+)
+
+type OutboxEmail struct {
+	ID            int          `db:"id"`
+	To            []string     `db:"recipients"`
+	Subject       string       `db:"subject"`
+	HTML          string       `db:"html"`
+	Text          string       `db:"text"`
+	Status        OutboxStatus `db:"status"`
+	Attempts      int          `db:"attempts"`
+	LastError     *string      `db:"last_error"`
+	NextAttemptAt time.Time    `db:"next_attempt_at"`
+	LockedUntil   *time.Time   `db:"locked_until"`
+	CreatedAt     time.Time    `db:"created_at"`
+	SentAt        *time.Time   `db:"sent_at"`
+}
+
+func (o *OutboxEmail) Mail() Mail {
+	return Mail{To: o.To, Subject: o.Subject, HTML: o.HTML, Text: o.Text}
+}
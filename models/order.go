@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderDelivered},
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	ID        int          `json:"id" db:"id"`
+	UserID    int          `json:"-" db:"user_id"`
+	Status    OrderStatus  `json:"status" db:"status"`
+	Total     int          `json:"total" db:"total"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time    `json:"updatedAt" db:"updated_at"`
+	Items     []*OrderItem `json:"items,omitempty" db:"-"`
+}
+
+type OrderItem struct {
+	ID        int    `json:"id" db:"id"`
+	OrderID   int    `json:"-" db:"order_id"`
+	ProductID int    `json:"productId" db:"product_id"`
+	VariantID int    `json:"variantId" db:"variant_id"`
+	Name      string `json:"name" db:"name"`
+	Size      string `json:"size" db:"size"`
+	Color     string `json:"color" db:"color"`
+	Quantity  int    `json:"quantity" db:"quantity"`
+	Price     int    `json:"price" db:"price"`
+}
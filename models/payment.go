@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	PaymentPending    PaymentStatus = "pending"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentSucceeded  PaymentStatus = "succeeded"
+	PaymentFailed     PaymentStatus = "failed"
+	PaymentRefunded   PaymentStatus = "refunded"
+)
+
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentPending:    {PaymentAuthorized, PaymentSucceeded, PaymentFailed},
+	PaymentAuthorized: {PaymentSucceeded, PaymentFailed},
+	PaymentSucceeded:  {PaymentRefunded},
+}
+
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, status := range paymentTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentStatusesBefore returns the statuses a payment may move to next from.
+// Gateway events can arrive out of order, so updates only apply from these.
+func PaymentStatusesBefore(next PaymentStatus) []PaymentStatus {
+	statuses := []PaymentStatus{}
+	for status := range paymentTransitions {
+		if status.CanTransitionTo(next) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+type Payment struct {
+	ID        int           `json:"id" db:"id"`
+	OrderID   int           `json:"orderId" db:"order_id"`
+	IntentID  string        `json:"intentId" db:"intent_id"`
+	Amount    int           `json:"amount" db:"amount"`
+	Status    PaymentStatus `json:"status" db:"status"`
+	CreatedAt time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time     `json:"updatedAt" db:"updated_at"`
+}
+
+type PaymentIntent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"clientSecret"`
+	OrderID      int    `json:"orderId"`
+	Amount       int    `json:"amount"`
+}
+
+type PaymentEvent struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	IntentID string `json:"intentId"`
+	OrderID  int    `json:"orderId"`
+	Amount   int    `json:"amount"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type Reservation struct {
+	ID        int       `db:"id"`
+	CartID    int       `db:"cart_id"`
+	VariantID int       `db:"variant_id"`
+	Quantity  int       `db:"quantity"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
@@ -1,7 +1,15 @@
 package models
 
+import "time"
+
+// Token is a device session. RefreshToken holds the hash of the session's
+// current refresh token, which is replaced on every refresh.
 type Token struct {
-	ID           int    `db:"id"`
-	UserID       string `db:"user_id"`
-	RefreshToken string `db:"refresh_token"`
+	ID           int       `db:"id"`
+	UserID       int       `db:"user_id"`
+	RefreshToken string    `db:"refresh_token"`
+	UserAgent    string    `db:"user_agent"`
+	IP           string    `db:"ip"`
+	CreatedAt    time.Time `db:"created_at"`
+	LastUsedAt   time.Time `db:"last_used_at"`
 }
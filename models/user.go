@@ -10,6 +10,11 @@ type User struct {
 	Password             string     `json:"-" db:"password"`
 	Name                 *string    `json:"name,omitempty" db:"name"`
 	Avatar               *string    `json:"avatar,omitempty" db:"avatar"`
+	VerifiedAt           *time.Time `json:"verifiedAt,omitempty" db:"verified_at"`
+	VerificationToken    *string    `json:"-" db:"verification_token"`
+	VerificationExpires  *time.Time `json:"-" db:"verification_expires"`
+	PasswordResetToken   *string    `json:"-" db:"password_reset_token"`
 	PasswordResetExpires *time.Time `json:"-" db:"password_reset_expires"`
 	Role                 []string   `json:"role" db:"role"`
+	Locale               string     `json:"locale" db:"locale"`
 }
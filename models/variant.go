@@ -0,0 +1,10 @@
+package models
+
+type ProductVariant struct {
+	ID        int    `json:"id" db:"id"`
+	ProductID int    `json:"productId" db:"product_id"`
+	Size      string `json:"size" db:"size"`
+	Color     string `json:"color" db:"color"`
+	SKU       string `json:"sku" db:"sku"`
+	Stock     int    `json:"stock" db:"stock"`
+}
@@ -1,264 +1,421 @@
 package processors
 
 import (
-	"bytes"
-	"errors"
-	"fmt"
+	"context"
 	"github.com/golang-jwt/jwt/v5"
-	"html/template"
+	"github.com/google/uuid"
+	"og-style/apperrors"
+	"og-style/config"
 	"og-style/db"
+	"og-style/metrics"
+	"og-style/models"
+	"og-style/services"
 	"og-style/types"
 	"og-style/utils"
 	"time"
 )
 
 type AuthProcessor interface {
-	SignUp(data types.CreateUser) error
-	SignIn(email, password string) (*types.SignInResponse, error)
-	RefreshTokens(refreshToken string) (*types.SignInResponse, error)
-	UpdatePassword(userId int, oldPassword, password string) error
-	ForgotPassword(email string) error
-	ResetPassword(email, password string) error
+	SignUp(ctx context.Context, data types.CreateUser) error
+	SignIn(ctx context.Context, email, password, userAgent, ip string) (*types.SignInResponse, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*types.SignInResponse, error)
+	UpdatePassword(ctx context.Context, userId int, oldPassword, password string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userId int) error
+	GetSessions(ctx context.Context, userId int, refreshToken string) ([]*types.Session, error)
+	DeleteSession(ctx context.Context, userId, sessionId int) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, userId int) error
 }
 
 type AuthPgProcessor struct {
 	UserStorage  db.UserStorage
 	CartStorage  db.CartStorage
 	TokenStorage db.TokenStorage
+	Tx           db.TxManager
+	Emails       services.EmailRenderer
+	Config       config.AuthConfig
 }
 
-func (a *AuthPgProcessor) SignUp(data types.CreateUser) error {
-	user, err := a.UserStorage.GetByEmail(data.Email)
-	if err != nil {
-		return err
-	}
-
-	if user.ID != 0 {
-		return fmt.Errorf("пользователь с эл.почтой %s уже существует", data.Email)
-	}
+var (
+	errUnauthorized     = apperrors.Unauthorized("unauthorized", "необходимо войти в аккаунт")
+	errInvalidResetLink = apperrors.Validation("invalid_reset_link", "ссылка для восстановления пароля недействительна или истекла")
+)
 
+func (a *AuthPgProcessor) SignUp(ctx context.Context, data types.CreateUser) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.SignUp")
+	defer span.End()
 	hashedPassword, hashErr := utils.HashPassword(data.Password)
 	if hashErr != nil {
-		return errors.New("что-то пошло не так.Повторите попытку чуть позже")
+		return apperrors.Internal(hashErr)
 	}
 
 	data.Password = hashedPassword
 
-	userId, createErr := a.UserStorage.Create(&data)
-	if createErr != nil {
-		return createErr
-	}
+	// The account, its cart and the welcome and verification emails commit
+	// together: a user is never created without being told, and a failed
+	// render leaves nothing behind.
+	err := a.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := a.UserStorage.GetByEmail(ctx, data.Email)
+		if err != nil {
+			return err
+		}
+
+		if user.ID != 0 {
+			return apperrors.Conflict("user_exists", "пользователь с эл.почтой %s уже существует", data.Email)
+		}
+
+		userId, err := a.UserStorage.Create(ctx, &data)
+		if err != nil {
+			return err
+		}
+
+		if err := a.CartStorage.Create(ctx, userId); err != nil {
+			return err
+		}
 
-	if err = a.CartStorage.Create(userId); err != nil {
+		if user, err = a.UserStorage.Get(ctx, userId); err != nil {
+			return err
+		}
+
+		welcome, err := a.Emails.Render(services.EmailWelcome, user.Locale, user.Email, nil)
+		if err != nil {
+			return err
+		}
+
+		return a.sendVerification(ctx, user, welcome)
+	})
+	if err != nil {
 		return err
 	}
+	metrics.SignUps.Inc()
 
 	return nil
 }
-func (a *AuthPgProcessor) SignIn(email, password string) (*types.SignInResponse, error) {
+func (a *AuthPgProcessor) SignIn(ctx context.Context, email, password, userAgent, ip string) (*types.SignInResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.SignIn")
+	defer span.End()
 
-	user, err := a.UserStorage.GetByEmail(email)
+	user, err := a.UserStorage.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
-	if user.ID == 0 {
-		return nil, errors.New("неправильный пароль или эл.адрес")
+	if user.ID == 0 || !utils.CheckPasswordHash(password, user.Password) {
+		metrics.SignIns.WithLabelValues("failure").Inc()
+		return nil, apperrors.Unauthorized("invalid_credentials", "неправильный пароль или эл.адрес")
 	}
 
-	if ok := utils.CheckPasswordHash(password, user.Password); !ok {
-		return nil, errors.New("неправильный пароль или эл.адрес")
+	sessionId, err := a.TokenStorage.Create(ctx, user.ID, userAgent, ip)
+	if err != nil {
+		return nil, apperrors.Internal(err)
 	}
 
-	var accessToken, refreshToken string
+	res, err := a.issueTokens(ctx, user, sessionId, "")
+	if err != nil {
+		return nil, err
+	}
 
-	if dbToken, err := a.TokenStorage.Get(user.ID); err != nil {
-		return nil, errors.New("что-то пошло не так.Повторите попытку чуть позже")
-	} else {
-		var res types.SignInResponse
-		var tokenError error
+	metrics.SignIns.WithLabelValues("success").Inc()
+	return res, nil
+}
 
-		accessToken, tokenError = utils.SignJWT(jwt.MapClaims{
-			"id":      user.ID,
-			"expires": time.Now().Add(time.Minute * 30),
-		})
+// RefreshTokens exchanges a refresh token for a new pair. Every refresh token
+// can be used once; presenting an already rotated one revokes its session.
+func (a *AuthPgProcessor) RefreshTokens(ctx context.Context, refreshToken string) (*types.SignInResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.RefreshTokens")
+	defer span.End()
 
-		if dbToken.ID != 0 {
-			refreshToken = dbToken.RefreshToken
-		} else {
-			refreshToken, _ = utils.SignJWT(jwt.MapClaims{
-				"id":      user.ID,
-				"expires": time.Now().Add(time.Hour * 24 * 30),
-			})
-
-			if err := a.TokenStorage.Create(user.ID, refreshToken); err != nil {
-				return nil, err
-			}
-		}
-
-		if tokenError != nil {
-			return nil, tokenError
-		}
+	token, err := utils.ParseJWT(refreshToken, a.Config.JWTSecret, utils.RefreshToken)
+	if err != nil {
+		return nil, errUnauthorized.Wrap(err)
+	}
 
-		res.AccessToken = accessToken
-		res.RefreshToken = refreshToken
-		res.User = user
+	userId, ok := utils.ClaimInt(token, "id")
+	if !ok {
+		return nil, errUnauthorized
+	}
 
-		return &res, nil
+	sessionId, ok := utils.ClaimInt(token, "sid")
+	if !ok {
+		return nil, errUnauthorized
 	}
-}
-func (a *AuthPgProcessor) RefreshTokens(refreshToken string) (*types.SignInResponse, error) {
 
-	token, err := utils.ParseJWT(refreshToken)
+	session, err := a.TokenStorage.Get(ctx, sessionId)
 	if err != nil {
 		return nil, err
 	}
 
-	if user, err := a.UserStorage.Get(int(token["id"].(float64))); err != nil {
-		return nil, err
-	} else {
-		if user.ID == 0 {
-			return nil, err
-		}
+	if session.ID == 0 || session.UserID != userId {
+		return nil, errUnauthorized
+	}
 
-		dbToken, err := a.TokenStorage.Get(user.ID)
-		if err != nil {
+	if session.RefreshToken != utils.HashToken(refreshToken) {
+		if err := a.TokenStorage.Delete(ctx, session.UserID, session.ID); err != nil {
 			return nil, err
 		}
+		return nil, apperrors.Unauthorized("refresh_token_reused", "токен уже был использован.Войдите в аккаунт заново")
+	}
 
-		if dbToken.RefreshToken != refreshToken {
-			return nil, err
-		}
+	user, err := a.UserStorage.Get(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
 
-		if accessToken, err := utils.SignJWT(jwt.MapClaims{
-			"id":      user.ID,
-			"expires": time.Now().Add(time.Minute * 30),
-		}); err != nil {
-			return nil, err
-		} else {
-			return &types.SignInResponse{
-				User:         user,
-				AccessToken:  accessToken,
-				RefreshToken: dbToken.RefreshToken,
-			}, nil
-		}
+	if user.ID == 0 {
+		return nil, errUnauthorized
 	}
+
+	return a.issueTokens(ctx, user, session.ID, session.RefreshToken)
 }
-func (a *AuthPgProcessor) UpdatePassword(userId int, oldPassword, password string) error {
+func (a *AuthPgProcessor) UpdatePassword(ctx context.Context, userId int, oldPassword, password string) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.UpdatePassword")
+	defer span.End()
 
-	user, err := a.UserStorage.Get(userId)
+	user, err := a.UserStorage.Get(ctx, userId)
 	if err != nil {
 		return err
 	}
 
 	if user.ID == 0 {
-		return fmt.Errorf("user with id %d doesn't exists", userId)
+		return apperrors.NotFound("user_not_found", "пользователь с ID %d не существует", userId)
 	}
 
 	if ok := utils.CheckPasswordHash(oldPassword, user.Password); !ok {
-		return errors.New("invalid password")
+		return apperrors.Validation("invalid_password", "неправильный пароль")
+	}
+
+	mail, err := a.Emails.Render(services.EmailPasswordChanged, user.Locale, user.Email, nil)
+	if err != nil {
+		return err
 	}
 
 	if hashedPassword, err := utils.HashPassword(password); err != nil {
 		return err
 	} else {
-		if err := a.UserStorage.UpdatePassword(userId, hashedPassword); err != nil {
+		if err := a.UserStorage.UpdatePassword(ctx, userId, hashedPassword, mail); err != nil {
 			return err
 		}
 		return nil
 	}
 
 }
-func (a *AuthPgProcessor) ForgotPassword(email string) error {
-	emailCh := make(chan error)
-	updateUserCh := make(chan error)
+func (a *AuthPgProcessor) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.ForgotPassword")
+	defer span.End()
+	user, err := a.UserStorage.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		return apperrors.NotFound("user_not_found", "пользователь с эл.почтой %s не существует", email)
+	}
 
-	user, err := a.UserStorage.GetByEmail(email)
+	resetToken, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	mail, err := a.Emails.Render(services.EmailResetPassword, user.Locale, user.Email, map[string]any{
+		"Token":    resetToken,
+		"ValidFor": a.Config.PasswordResetTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := a.UserStorage.SetPasswordResetToken(ctx, user.ID, utils.HashToken(resetToken), time.Now().Add(a.Config.PasswordResetTTL), mail); err != nil {
+		return apperrors.Internal(err)
+	}
+	return nil
+}
+func (a *AuthPgProcessor) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.ResetPassword")
+	defer span.End()
+	user, err := a.UserStorage.GetByPasswordResetToken(ctx, utils.HashToken(token))
 	if err != nil {
 		return err
 	}
 
 	if user.ID == 0 {
-		return fmt.Errorf("пользователь с эл.почтой %s не существует", email)
+		return errInvalidResetLink
 	}
 
-	templateParser, tempErr := template.ParseFiles("./templates/forgot-password.html")
-	if tempErr != nil {
-		return tempErr
+	mail, err := a.Emails.Render(services.EmailPasswordChanged, user.Locale, user.Email, nil)
+	if err != nil {
+		return err
 	}
 
-	buff := &bytes.Buffer{}
-	if err := templateParser.Execute(buff, struct{ Email, Token string }{
-		Email: email,
-	}); err != nil {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
 		return err
 	}
 
-	go func() {
-		if err := utils.SendEmail(buff.String(), "Reset password", email); err != nil {
-			emailCh <- err
-			return
+	// The new password, the notification and signing out every session
+	// commit together, so a failure cannot leave old sessions alive.
+	return a.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		userId, err := a.UserStorage.ResetPassword(ctx, utils.HashToken(token), hashedPassword, mail)
+		if err != nil {
+			return err
 		}
-		emailCh <- nil
-	}()
 
-	go func() {
-		if err := a.UserStorage.UpdatePasswordExpires(user.ID, time.Now().Add(time.Minute*15)); err != nil {
-			updateUserCh <- err
-			return
+		if userId == 0 {
+			return errInvalidResetLink
 		}
-		updateUserCh <- nil
-	}()
 
-	if emailErr, updateUserErr := <-emailCh, <-updateUserCh; emailErr != nil || updateUserErr != nil {
-		fmt.Println(emailErr)
-		fmt.Println(updateUserErr)
-		return errors.New("что-то пошло не так.Повторите попытку чуть позже")
+		return a.TokenStorage.DeleteAll(ctx, userId)
+	})
+}
+func (a *AuthPgProcessor) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.Logout")
+	defer span.End()
+	token, err := utils.ParseJWT(refreshToken, a.Config.JWTSecret, utils.RefreshToken)
+	if err != nil {
+		return nil
 	}
-	return nil
+
+	userId, ok := utils.ClaimInt(token, "id")
+	if !ok {
+		return errUnauthorized
+	}
+
+	sessionId, ok := utils.ClaimInt(token, "sid")
+	if !ok {
+		return errUnauthorized
+	}
+
+	return a.TokenStorage.Delete(ctx, userId, sessionId)
+}
+
+// LogoutAll revokes every session of the user, so none of their refresh
+// tokens can be exchanged again. Those tokens are never accepted as access
+// tokens; access tokens already issued lapse after AccessTokenTTL.
+func (a *AuthPgProcessor) LogoutAll(ctx context.Context, userId int) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.LogoutAll")
+	defer span.End()
+	return a.TokenStorage.DeleteAll(ctx, userId)
+}
+func (a *AuthPgProcessor) GetSessions(ctx context.Context, userId int, refreshToken string) ([]*types.Session, error) {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.GetSessions")
+	defer span.End()
+	tokens, err := a.TokenStorage.GetAll(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := utils.HashToken(refreshToken)
+	sessions := make([]*types.Session, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, &types.Session{
+			ID:         token.ID,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			Current:    token.RefreshToken == current,
+		})
+	}
+
+	return sessions, nil
+}
+func (a *AuthPgProcessor) DeleteSession(ctx context.Context, userId, sessionId int) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.DeleteSession")
+	defer span.End()
+	session, err := a.TokenStorage.Get(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+
+	if session.ID == 0 || session.UserID != userId {
+		return apperrors.NotFound("session_not_found", "сессия с ID %d не существует", sessionId)
+	}
+
+	return a.TokenStorage.Delete(ctx, userId, sessionId)
 }
-func (a *AuthPgProcessor) ResetPassword(email, password string) error {
-	deleteExpiresCh := make(chan error)
-	updatePasswordCh := make(chan error)
+func (a *AuthPgProcessor) issueTokens(ctx context.Context, user *models.User, sessionId int, oldToken string) (*types.SignInResponse, error) {
+	accessToken, err := utils.SignJWT(jwt.MapClaims{
+		"id":      user.ID,
+		"typ":     utils.AccessToken,
+		"expires": time.Now().Add(a.Config.AccessTokenTTL),
+	}, a.Config.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.SignJWT(jwt.MapClaims{
+		"id":      user.ID,
+		"typ":     utils.RefreshToken,
+		"sid":     sessionId,
+		"jti":     uuid.NewString(),
+		"expires": time.Now().Add(a.Config.RefreshTokenTTL),
+	}, a.Config.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
 
-	user, err := a.UserStorage.GetByEmail(email)
+	if ok, err := a.TokenStorage.Rotate(ctx, sessionId, oldToken, utils.HashToken(refreshToken)); err != nil {
+		return nil, err
+	} else if !ok {
+		if err := a.TokenStorage.Delete(ctx, user.ID, sessionId); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.Unauthorized("refresh_token_reused", "токен уже был использован.Войдите в аккаунт заново")
+	}
+
+	return &types.SignInResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+func (a *AuthPgProcessor) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.VerifyEmail")
+	defer span.End()
+	userId, err := a.UserStorage.VerifyEmail(ctx, utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if userId == 0 {
+		return apperrors.Validation("invalid_verification_link", "ссылка для подтверждения эл.почты недействительна или истекла")
+	}
+
+	return nil
+}
+func (a *AuthPgProcessor) ResendVerification(ctx context.Context, userId int) error {
+	ctx, span := tracer.Start(ctx, "AuthProcessor.ResendVerification")
+	defer span.End()
+	user, err := a.UserStorage.Get(ctx, userId)
 	if err != nil {
 		return err
 	}
 
 	if user.ID == 0 {
-		return fmt.Errorf("пользователь с эл.почтой %s не существует", email)
+		return apperrors.NotFound("user_not_found", "пользователь с ID %d не существует", userId)
 	}
 
-	fmt.Println(user)
-	if time.Now().After(user.PasswordResetExpires.Add(time.Second * 0)) {
-		return errors.New("время восстановления пароля истек")
+	if user.VerifiedAt != nil {
+		return apperrors.Conflict("email_already_verified", "эл.почта уже подтверждена")
 	}
 
-	if hashedPassword, err := utils.HashPassword(password); err != nil {
+	return a.sendVerification(ctx, user)
+}
+func (a *AuthPgProcessor) sendVerification(ctx context.Context, user *models.User, mails ...models.Mail) error {
+	verificationToken, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	mail, err := a.Emails.Render(services.EmailVerify, user.Locale, user.Email, map[string]any{
+		"Email": user.Email,
+		"Token": verificationToken,
+	})
+	if err != nil {
 		return err
-	} else {
-		go func() {
-			if err := a.UserStorage.UpdatePassword(user.ID, hashedPassword); err != nil {
-				updatePasswordCh <- err
-				return
-			}
-			updatePasswordCh <- nil
-		}()
-		go func() {
-			if err := a.UserStorage.DeletePasswordResetExpires(user.ID); err != nil {
-				deleteExpiresCh <- err
-				return
-			}
-			deleteExpiresCh <- nil
-		}()
-
-		if deleteErr, updateErr := <-deleteExpiresCh, <-updatePasswordCh; deleteErr != nil || updateErr != nil {
-			fmt.Println(deleteErr)
-			fmt.Println(updateErr)
-			return errors.New("что-то пошло не так.Повторите попытку чуть позже")
-		}
-		return nil
 	}
 
+	return a.UserStorage.SetVerificationToken(ctx, user.ID, utils.HashToken(verificationToken), time.Now().Add(a.Config.EmailVerificationTTL), append(mails, mail)...)
 }
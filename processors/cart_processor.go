@@ -0,0 +1,181 @@
+package processors
+
+import (
+	"context"
+	"og-style/apperrors"
+	"og-style/db"
+	"og-style/models"
+	"og-style/services"
+	"og-style/types"
+)
+
+// maxItemQuantity mirrors the max=99 limit on types.AddCartItem.Quantity.
+const maxItemQuantity = 99
+
+type CartProcessor interface {
+	Get(ctx context.Context, userId int) (*types.Cart, error)
+	AddItem(ctx context.Context, userId int, data *types.AddCartItem) error
+	UpdateItem(ctx context.Context, userId, itemId int, data *types.UpdateCartItem) error
+	DeleteItem(ctx context.Context, userId, itemId int) error
+	Clear(ctx context.Context, userId int) error
+}
+
+type CartPgProcessor struct {
+	CartStorage    db.CartStorage
+	ProductStorage db.ProductStorage
+	VariantStorage db.VariantStorage
+	Inventory      services.InventoryService
+	Tx             db.TxManager
+}
+
+func (c *CartPgProcessor) Get(ctx context.Context, userId int) (*types.Cart, error) {
+	ctx, span := tracer.Start(ctx, "CartProcessor.Get")
+	defer span.End()
+	cart, err := c.getCart(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := c.CartStorage.GetItems(ctx, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := types.Cart{ID: cart.ID, Items: items}
+	for _, item := range items {
+		price := item.Price
+		if item.DiscountedPrice != nil {
+			price = *item.DiscountedPrice
+		}
+
+		item.Subtotal = price * item.Quantity
+		res.Total += item.Subtotal
+		res.TotalQuantity += item.Quantity
+	}
+
+	return &res, nil
+}
+func (c *CartPgProcessor) AddItem(ctx context.Context, userId int, data *types.AddCartItem) error {
+	ctx, span := tracer.Start(ctx, "CartProcessor.AddItem")
+	defer span.End()
+	cart, err := c.getCart(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	product, err := c.ProductStorage.Get(ctx, data.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if product.ID == 0 {
+		return apperrors.NotFound("product_not_found", "продукт с ID %d не существует", data.ProductID)
+	}
+
+	variant, err := c.VariantStorage.GetByOptions(ctx, product.ID, data.Size, data.Color)
+	if err != nil {
+		return err
+	}
+
+	if variant.ID == 0 {
+		return apperrors.Validation("variant_unavailable", "вариант с размером %s и цветом %s недоступен для этого продукта", data.Size, data.Color)
+	}
+
+	// The cart is locked while the line is read, reserved for and written, so
+	// concurrent adds see each other's quantity, and a failed write releases
+	// the reservation with the rollback instead of holding stock until it
+	// expires.
+	return c.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := c.CartStorage.Lock(ctx, cart.ID); err != nil {
+			return err
+		}
+
+		item, err := c.CartStorage.GetItemByVariant(ctx, cart.ID, variant.ID)
+		if err != nil {
+			return err
+		}
+
+		// Adding to an existing line must respect the same max=99 the request
+		// validation enforces on a single quantity.
+		if item.Quantity+data.Quantity > maxItemQuantity {
+			return apperrors.Validation("cart_item_quantity_exceeded", "в корзине может быть не больше %d единиц одного товара", maxItemQuantity)
+		}
+
+		if err := c.Inventory.Reserve(ctx, cart.ID, variant.ID, item.Quantity+data.Quantity); err != nil {
+			return err
+		}
+
+		return c.CartStorage.AddItem(ctx, cart.ID, variant.ID, data)
+	})
+}
+func (c *CartPgProcessor) UpdateItem(ctx context.Context, userId, itemId int, data *types.UpdateCartItem) error {
+	ctx, span := tracer.Start(ctx, "CartProcessor.UpdateItem")
+	defer span.End()
+	cart, item, err := c.getItem(ctx, userId, itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Inventory.Reserve(ctx, cart.ID, item.VariantID, data.Quantity); err != nil {
+		return err
+	}
+
+	return c.CartStorage.UpdateItemQuantity(ctx, cart.ID, itemId, data.Quantity)
+}
+func (c *CartPgProcessor) DeleteItem(ctx context.Context, userId, itemId int) error {
+	ctx, span := tracer.Start(ctx, "CartProcessor.DeleteItem")
+	defer span.End()
+	cart, item, err := c.getItem(ctx, userId, itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := c.CartStorage.DeleteItem(ctx, cart.ID, itemId); err != nil {
+		return err
+	}
+
+	return c.Inventory.Release(ctx, cart.ID, item.VariantID)
+}
+func (c *CartPgProcessor) Clear(ctx context.Context, userId int) error {
+	ctx, span := tracer.Start(ctx, "CartProcessor.Clear")
+	defer span.End()
+	cart, err := c.getCart(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := c.CartStorage.Clear(ctx, cart.ID); err != nil {
+		return err
+	}
+
+	return c.Inventory.ReleaseAll(ctx, cart.ID)
+}
+func (c *CartPgProcessor) getCart(ctx context.Context, userId int) (*models.Cart, error) {
+	cart, err := c.CartStorage.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cart.ID == 0 {
+		return nil, apperrors.NotFound("cart_not_found", "корзина не найдена")
+	}
+
+	return cart, nil
+}
+func (c *CartPgProcessor) getItem(ctx context.Context, userId, itemId int) (*models.Cart, *models.CartItem, error) {
+	cart, err := c.getCart(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	item, err := c.CartStorage.GetItem(ctx, cart.ID, itemId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if item.ID == 0 {
+		return nil, nil, apperrors.NotFound("cart_item_not_found", "товар с ID %d не найден в корзине", itemId)
+	}
+
+	return cart, item, nil
+}
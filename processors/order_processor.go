@@ -0,0 +1,152 @@
+package processors
+
+import (
+	"context"
+	"og-style/apperrors"
+	"og-style/db"
+	"og-style/metrics"
+	"og-style/models"
+	"og-style/services"
+)
+
+type OrderProcessor interface {
+	Create(ctx context.Context, userId int) (*models.Order, error)
+	Get(ctx context.Context, userId, id int) (*models.Order, error)
+	GetAll(ctx context.Context, userId int) ([]*models.Order, error)
+	UpdateStatus(ctx context.Context, id int, status models.OrderStatus) error
+}
+
+type OrderPgProcessor struct {
+	OrderStorage  db.OrderStorage
+	CartStorage   db.CartStorage
+	UserStorage   db.UserStorage
+	OutboxStorage db.OutboxStorage
+	Tx            db.TxManager
+	Emails        services.EmailRenderer
+}
+
+func (o *OrderPgProcessor) Create(ctx context.Context, userId int) (*models.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderProcessor.Create")
+	defer span.End()
+	cart, err := o.CartStorage.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cart.ID == 0 {
+		return nil, apperrors.NotFound("cart_not_found", "корзина не найдена")
+	}
+
+	// The order and its confirmation email are written together: an order is
+	// never placed without the customer being told about it.
+	var order *models.Order
+	err = o.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		orderId, err := o.OrderStorage.CreateFromCart(ctx, userId, cart.ID)
+		if err != nil {
+			return err
+		}
+
+		if order, err = o.Get(ctx, userId, orderId); err != nil {
+			return err
+		}
+
+		mail, err := o.renderOrderEmail(ctx, services.EmailOrderConfirmation, order)
+		if err != nil {
+			return err
+		}
+
+		return o.OutboxStorage.Enqueue(ctx, *mail)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.OrdersPlaced.Inc()
+
+	return order, nil
+}
+func (o *OrderPgProcessor) Get(ctx context.Context, userId, id int) (*models.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderProcessor.Get")
+	defer span.End()
+	order, err := o.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserID != userId {
+		return nil, apperrors.NotFound("order_not_found", "заказ с ID %d не существует", id)
+	}
+
+	if order.Items, err = o.OrderStorage.GetItems(ctx, order.ID); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+func (o *OrderPgProcessor) GetAll(ctx context.Context, userId int) ([]*models.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderProcessor.GetAll")
+	defer span.End()
+	orders, err := o.OrderStorage.GetAll(ctx, userId)
+	if err != nil {
+		return orders, err
+	}
+
+	return orders, nil
+}
+func (o *OrderPgProcessor) UpdateStatus(ctx context.Context, id int, status models.OrderStatus) error {
+	ctx, span := tracer.Start(ctx, "OrderProcessor.UpdateStatus")
+	defer span.End()
+	order, err := o.getOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if status == models.OrderPaid {
+		return apperrors.Validation("order_status_invalid", "статус оплачен устанавливается только после подтверждения платежа")
+	}
+
+	if order.Status == models.OrderPaid && status == models.OrderCancelled {
+		return apperrors.Conflict("order_paid", "для отмены оплаченного заказа оформите возврат")
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return apperrors.Conflict("order_status_transition", "невозможно изменить статус заказа с %s на %s", order.Status, status)
+	}
+
+	var mail *models.Mail
+	if status == models.OrderShipped {
+		if mail, err = o.renderOrderEmail(ctx, services.EmailOrderShipped, order); err != nil {
+			return err
+		}
+	}
+
+	return o.OrderStorage.UpdateStatus(ctx, id, order.Status, status, mail)
+}
+func (o *OrderPgProcessor) getOrder(ctx context.Context, id int) (*models.Order, error) {
+	order, err := o.OrderStorage.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.ID == 0 {
+		return nil, apperrors.NotFound("order_not_found", "заказ с ID %d не существует", id)
+	}
+
+	return order, nil
+}
+func (o *OrderPgProcessor) renderOrderEmail(ctx context.Context, name string, order *models.Order) (*models.Mail, error) {
+	user, err := o.UserStorage.Get(ctx, order.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ID == 0 {
+		return nil, apperrors.NotFound("user_not_found", "пользователь с ID %d не существует", order.UserID)
+	}
+
+	mail, err := o.Emails.Render(name, user.Locale, user.Email, map[string]any{"Order": order})
+	if err != nil {
+		return nil, err
+	}
+
+	return &mail, nil
+}
@@ -0,0 +1,159 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"og-style/apperrors"
+	"og-style/db"
+	"og-style/models"
+	"og-style/services"
+)
+
+type PaymentProcessor interface {
+	CreateIntent(ctx context.Context, userId, orderId int) (*models.PaymentIntent, error)
+	HandleWebhook(ctx context.Context, payload []byte, signature string) error
+	Refund(ctx context.Context, orderId int) error
+}
+
+type PaymentPgProcessor struct {
+	PaymentStorage db.PaymentStorage
+	OrderStorage   db.OrderStorage
+	Provider       services.PaymentProvider
+	Tx             db.TxManager
+}
+
+func (p *PaymentPgProcessor) CreateIntent(ctx context.Context, userId, orderId int) (*models.PaymentIntent, error) {
+	ctx, span := tracer.Start(ctx, "PaymentProcessor.CreateIntent")
+	defer span.End()
+	order, err := p.OrderStorage.Get(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.ID == 0 || order.UserID != userId {
+		return nil, apperrors.NotFound("order_not_found", "заказ с ID %d не существует", orderId)
+	}
+
+	if order.Status != models.OrderPending {
+		return nil, apperrors.Conflict("order_not_payable", "заказ с ID %d уже оплачен или отменен", orderId)
+	}
+
+	// Checked before asking the gateway for an intent; Create checks again in
+	// case of a concurrent request.
+	payment, err := p.PaymentStorage.GetByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.ID != 0 && payment.Status != models.PaymentFailed {
+		return nil, db.ErrPaymentExists
+	}
+
+	intent, err := p.Provider.CreateIntent(order.ID, order.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.PaymentStorage.Create(ctx, order.ID, intent.ID, intent.Amount); err != nil {
+		return nil, err
+	}
+
+	return intent, nil
+}
+
+// HandleWebhook verifies and applies a gateway event. Events that were
+// already applied are acknowledged without changing the payment. An
+// authorized payment is captured only after the event is committed, keeping
+// the gateway call out of the retried transaction; while the payment is
+// still authorized a replayed event captures again, which retries a failed
+// capture and is a no-op at the gateway otherwise.
+func (p *PaymentPgProcessor) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	ctx, span := tracer.Start(ctx, "PaymentProcessor.HandleWebhook")
+	defer span.End()
+	event, err := p.Provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	payment, err := p.PaymentStorage.GetByIntent(ctx, event.IntentID)
+	if err != nil {
+		return err
+	}
+
+	if payment.ID == 0 {
+		return apperrors.NotFound("payment_not_found", "платеж %s не найден", event.IntentID)
+	}
+
+	var status models.PaymentStatus
+	switch event.Type {
+	case services.PaymentEventAuthorized:
+		status = models.PaymentAuthorized
+	case services.PaymentEventSucceeded:
+		if event.Amount != payment.Amount {
+			return apperrors.Conflict("payment_amount_mismatch", "сумма платежа %s не совпадает с суммой заказа", event.IntentID)
+		}
+		status = models.PaymentSucceeded
+	case services.PaymentEventFailed:
+		status = models.PaymentFailed
+	default:
+		return nil
+	}
+
+	if _, err := p.PaymentStorage.ApplyEvent(ctx, event.ID, event.IntentID, status); err != nil {
+		return err
+	}
+
+	if status != models.PaymentAuthorized {
+		return nil
+	}
+
+	if payment, err = p.PaymentStorage.GetByIntent(ctx, event.IntentID); err != nil {
+		return err
+	}
+
+	if payment.Status != models.PaymentAuthorized {
+		return nil
+	}
+
+	return p.Provider.Capture(event.IntentID)
+}
+func (p *PaymentPgProcessor) Refund(ctx context.Context, orderId int) error {
+	ctx, span := tracer.Start(ctx, "PaymentProcessor.Refund")
+	defer span.End()
+	order, err := p.OrderStorage.Get(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.ID == 0 {
+		return apperrors.NotFound("order_not_found", "заказ с ID %d не существует", orderId)
+	}
+
+	if order.Status != models.OrderPaid {
+		return apperrors.Conflict("order_not_refundable", "возврат возможен только для оплаченного заказа")
+	}
+
+	payment, err := p.PaymentStorage.GetByOrder(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	if payment.ID == 0 || payment.Status != models.PaymentSucceeded {
+		return apperrors.NotFound("payment_not_found", "платеж для заказа с ID %d не найден", orderId)
+	}
+
+	// The gateway is called before, and outside, the transaction that records
+	// the refund. The key is fixed per payment, so if recording fails the
+	// retried request does not refund the customer twice.
+	if err := p.Provider.Refund(payment.IntentID, payment.Amount, fmt.Sprintf("refund-payment-%d", payment.ID)); err != nil {
+		return err
+	}
+
+	return p.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := p.PaymentStorage.UpdateStatus(ctx, payment.ID, models.PaymentRefunded); err != nil {
+			return err
+		}
+
+		return p.OrderStorage.UpdateStatus(ctx, orderId, models.OrderPaid, models.OrderCancelled, nil)
+	})
+}
@@ -1,8 +1,9 @@
 package processors
 
 import (
-	"fmt"
+	"context"
 	"mime/multipart"
+	"og-style/apperrors"
 	"og-style/db"
 	"og-style/models"
 	"og-style/services"
@@ -10,13 +11,13 @@ import (
 )
 
 type ProductProcessor interface {
-	Get(id int) (models.Product, error)
-	GetAll(params types.GetProductsParams) ([]*models.Product, error)
-	Create(data *types.CreateProduct) error
-	Update(id int, data *types.UpdateProduct) error
-	Delete(id int) error
-	UploadImage(file multipart.File) (string, error)
-	GetFilters(category string) (types.ProductFilters, error)
+	Get(ctx context.Context, id int) (models.Product, error)
+	GetAll(ctx context.Context, params types.GetProductsParams) ([]*models.Product, error)
+	Create(ctx context.Context, data *types.CreateProduct) error
+	Update(ctx context.Context, id int, data *types.UpdateProduct) error
+	Delete(ctx context.Context, id int) error
+	UploadImage(ctx context.Context, file multipart.File) (string, error)
+	GetFilters(ctx context.Context, category string, inStock bool) (types.ProductFilters, error)
 }
 
 type ProductPgProcessor struct {
@@ -24,63 +25,73 @@ type ProductPgProcessor struct {
 	ImageUploader  services.ImageUploaderService
 }
 
-func (p *ProductPgProcessor) Get(id int) (models.Product, error) {
+func (p *ProductPgProcessor) Get(ctx context.Context, id int) (models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.Get")
+	defer span.End()
 
-	product, err := p.ProductStorage.Get(id)
+	product, err := p.ProductStorage.Get(ctx, id)
 	if err != nil {
 		return product, err
 	}
 
 	if product.ID == 0 {
-		return product, fmt.Errorf("продукт с ID %d не существует", id)
+		return product, apperrors.NotFound("product_not_found", "продукт с ID %d не существует", id)
 	}
 
 	return product, nil
 }
-func (p *ProductPgProcessor) GetAll(params types.GetProductsParams) ([]*models.Product, error) {
+func (p *ProductPgProcessor) GetAll(ctx context.Context, params types.GetProductsParams) ([]*models.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.GetAll")
+	defer span.End()
 
-	products, err := p.ProductStorage.GetAll(params)
+	products, err := p.ProductStorage.GetAll(ctx, params)
 	if err != nil {
 		return products, err
 	}
 
 	return products, nil
 }
-func (p *ProductPgProcessor) Create(data *types.CreateProduct) error {
-	if err := p.ProductStorage.Create(data); err != nil {
+func (p *ProductPgProcessor) Create(ctx context.Context, data *types.CreateProduct) error {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.Create")
+	defer span.End()
+	if _, err := p.ProductStorage.Create(ctx, data); err != nil {
 		return err
 	}
 	return nil
 }
-func (p *ProductPgProcessor) Update(id int, data *types.UpdateProduct) error {
+func (p *ProductPgProcessor) Update(ctx context.Context, id int, data *types.UpdateProduct) error {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.Update")
+	defer span.End()
 
-	product, err := p.Get(id)
+	product, err := p.Get(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if product.ID == 0 {
-		return fmt.Errorf("продукт с ID %d не существует", id)
+		return apperrors.NotFound("product_not_found", "продукт с ID %d не существует", id)
 	}
 
-	err = p.ProductStorage.Update(id, data)
+	err = p.ProductStorage.Update(ctx, id, data)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
-func (p *ProductPgProcessor) Delete(id int) error {
-	product, err := p.Get(id)
+func (p *ProductPgProcessor) Delete(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.Delete")
+	defer span.End()
+	product, err := p.Get(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	if product.ID == 0 {
-		return fmt.Errorf("продукт с ID %d не существует", id)
+		return apperrors.NotFound("product_not_found", "продукт с ID %d не существует", id)
 	}
 
-	err = p.ProductStorage.Delete(id)
+	err = p.ProductStorage.Delete(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -88,16 +99,20 @@ func (p *ProductPgProcessor) Delete(id int) error {
 	return nil
 }
 
-func (p *ProductPgProcessor) UploadImage(file multipart.File) (string, error) {
-	if imgUrl, err := p.ImageUploader.Upload(file); err != nil {
+func (p *ProductPgProcessor) UploadImage(ctx context.Context, file multipart.File) (string, error) {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.UploadImage")
+	defer span.End()
+	if imgUrl, err := p.ImageUploader.Upload(ctx, file); err != nil {
 		return "", err
 	} else {
 		return imgUrl, nil
 	}
 }
 
-func (p *ProductPgProcessor) GetFilters(category string) (types.ProductFilters, error) {
-	if filters, err := p.ProductStorage.GetFilters(category); err != nil {
+func (p *ProductPgProcessor) GetFilters(ctx context.Context, category string, inStock bool) (types.ProductFilters, error) {
+	ctx, span := tracer.Start(ctx, "ProductProcessor.GetFilters")
+	defer span.End()
+	if filters, err := p.ProductStorage.GetFilters(ctx, category, inStock); err != nil {
 		return filters, err
 	} else {
 		return filters, nil
@@ -0,0 +1,7 @@
+package processors
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts a span in every exported processor method, named
+// <Interface>.<Method>.
+var tracer = otel.Tracer("og-style/processors")
@@ -0,0 +1,126 @@
+package processors
+
+import (
+	"context"
+	"og-style/apperrors"
+	"og-style/db"
+	"og-style/models"
+	"og-style/types"
+	"slices"
+)
+
+type VariantProcessor interface {
+	GetAll(ctx context.Context, productId int) ([]*models.ProductVariant, error)
+	Create(ctx context.Context, productId int, data *types.CreateVariant) (*models.ProductVariant, error)
+	Update(ctx context.Context, productId, id int, data *types.UpdateVariant) error
+	Delete(ctx context.Context, productId, id int) error
+}
+
+type VariantPgProcessor struct {
+	VariantStorage db.VariantStorage
+	ProductStorage db.ProductStorage
+}
+
+func (v *VariantPgProcessor) GetAll(ctx context.Context, productId int) ([]*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "VariantProcessor.GetAll")
+	defer span.End()
+	if _, err := v.getProduct(ctx, productId); err != nil {
+		return nil, err
+	}
+
+	return v.VariantStorage.GetAll(ctx, productId)
+}
+func (v *VariantPgProcessor) Create(ctx context.Context, productId int, data *types.CreateVariant) (*models.ProductVariant, error) {
+	ctx, span := tracer.Start(ctx, "VariantProcessor.Create")
+	defer span.End()
+	product, err := v.getProduct(ctx, productId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(product.Size, data.Size) {
+		return nil, apperrors.Validation("size_unavailable", "размер %s недоступен для этого продукта", data.Size)
+	}
+
+	if !slices.Contains(product.Colors, data.Color) {
+		return nil, apperrors.Validation("color_unavailable", "цвет %s недоступен для этого продукта", data.Color)
+	}
+
+	if variant, err := v.VariantStorage.GetByOptions(ctx, productId, data.Size, data.Color); err != nil {
+		return nil, err
+	} else if variant.ID != 0 {
+		return nil, apperrors.Conflict("variant_exists", "вариант с размером %s и цветом %s уже существует", data.Size, data.Color)
+	}
+
+	if err := v.checkSKU(ctx, data.SKU); err != nil {
+		return nil, err
+	}
+
+	variantId, err := v.VariantStorage.Create(ctx, productId, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.VariantStorage.Get(ctx, productId, variantId)
+}
+func (v *VariantPgProcessor) Update(ctx context.Context, productId, id int, data *types.UpdateVariant) error {
+	ctx, span := tracer.Start(ctx, "VariantProcessor.Update")
+	defer span.End()
+	variant, err := v.getVariant(ctx, productId, id)
+	if err != nil {
+		return err
+	}
+
+	if data.SKU != "" && data.SKU != variant.SKU {
+		if err := v.checkSKU(ctx, data.SKU); err != nil {
+			return err
+		}
+	}
+
+	return v.VariantStorage.Update(ctx, productId, id, data)
+}
+func (v *VariantPgProcessor) Delete(ctx context.Context, productId, id int) error {
+	ctx, span := tracer.Start(ctx, "VariantProcessor.Delete")
+	defer span.End()
+	if _, err := v.getVariant(ctx, productId, id); err != nil {
+		return err
+	}
+
+	return v.VariantStorage.Delete(ctx, productId, id)
+}
+func (v *VariantPgProcessor) getProduct(ctx context.Context, productId int) (models.Product, error) {
+	product, err := v.ProductStorage.Get(ctx, productId)
+	if err != nil {
+		return product, err
+	}
+
+	if product.ID == 0 {
+		return product, apperrors.NotFound("product_not_found", "продукт с ID %d не существует", productId)
+	}
+
+	return product, nil
+}
+func (v *VariantPgProcessor) getVariant(ctx context.Context, productId, id int) (*models.ProductVariant, error) {
+	variant, err := v.VariantStorage.Get(ctx, productId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if variant.ID == 0 {
+		return nil, apperrors.NotFound("variant_not_found", "вариант с ID %d не существует", id)
+	}
+
+	return variant, nil
+}
+func (v *VariantPgProcessor) checkSKU(ctx context.Context, sku string) error {
+	variant, err := v.VariantStorage.GetBySKU(ctx, sku)
+	if err != nil {
+		return err
+	}
+
+	if variant.ID != 0 {
+		return apperrors.Conflict("sku_exists", "вариант с артикулом %s уже существует", sku)
+	}
+
+	return nil
+}
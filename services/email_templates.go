@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"og-style/models"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	EmailWelcome           = "welcome"
+	EmailVerify            = "verify-email"
+	EmailResetPassword     = "reset-password"
+	EmailPasswordChanged   = "password-changed"
+	EmailOrderConfirmation = "order-confirmation"
+	EmailOrderShipped      = "order-shipped"
+
+	DefaultLocale = "ru"
+)
+
+var (
+	emailNames = []string{EmailWelcome, EmailVerify, EmailResetPassword, EmailPasswordChanged, EmailOrderConfirmation, EmailOrderShipped}
+	Locales    = []string{"ru", "en"}
+)
+
+type EmailRenderer interface {
+	Render(name, locale, to string, data map[string]any) (models.Mail, error)
+}
+
+// TemplateEmailRenderer renders the email catalog from Dir, which holds a
+// shared layout.html/layout.txt and one directory per locale with an .html
+// and a .txt template for every email. The .txt template also defines the
+// subject.
+type TemplateEmailRenderer struct {
+	BaseURL string
+
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+func NewTemplateEmailRenderer(dir, baseURL string) (*TemplateEmailRenderer, error) {
+	t := TemplateEmailRenderer{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		html:    make(map[string]*htmltemplate.Template),
+		text:    make(map[string]*texttemplate.Template),
+	}
+
+	for _, locale := range Locales {
+		for _, name := range emailNames {
+			funcs := templateFuncs(locale)
+
+			html, err := htmltemplate.New("layout.html").Funcs(funcs).ParseFiles(filepath.Join(dir, "layout.html"), filepath.Join(dir, locale, name+".html"))
+			if err != nil {
+				return nil, err
+			}
+
+			text, err := texttemplate.New("layout.txt").Funcs(funcs).ParseFiles(filepath.Join(dir, "layout.txt"), filepath.Join(dir, locale, name+".txt"))
+			if err != nil {
+				return nil, err
+			}
+
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s/%s.txt does not define a subject", locale, name)
+			}
+
+			t.html[locale+"/"+name] = html
+			t.text[locale+"/"+name] = text
+		}
+	}
+
+	return &t, nil
+}
+
+// Render renders the email name in locale, falling back to DefaultLocale for
+// unsupported locales. BaseURL and Locale are added to data.
+func (t *TemplateEmailRenderer) Render(name, locale, to string, data map[string]any) (models.Mail, error) {
+	key := locale + "/" + name
+	if _, ok := t.html[key]; !ok {
+		locale = DefaultLocale
+		key = locale + "/" + name
+	}
+
+	html, ok := t.html[key]
+	if !ok {
+		return models.Mail{}, fmt.Errorf("unknown email template %s", name)
+	}
+	text := t.text[key]
+
+	vars := map[string]any{"BaseURL": t.BaseURL, "Locale": locale}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	var subject, htmlBody, textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", vars); err != nil {
+		return models.Mail{}, err
+	}
+	if err := html.ExecuteTemplate(&htmlBody, "layout", vars); err != nil {
+		return models.Mail{}, err
+	}
+	if err := text.ExecuteTemplate(&textBody, "layout", vars); err != nil {
+		return models.Mail{}, err
+	}
+
+	return models.Mail{
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    htmlBody.String(),
+		Text:    textBody.String(),
+	}, nil
+}
+
+// durationUnits holds the singular, few and many forms of each unit by locale.
+var durationUnits = map[string]map[time.Duration][3]string{
+	"ru": {time.Hour: {"час", "часа", "часов"}, time.Minute: {"минуту", "минуты", "минут"}},
+	"en": {time.Hour: {"hour", "hours", "hours"}, time.Minute: {"minute", "minutes", "minutes"}},
+}
+
+// templateFuncs are the functions available to the templates of locale:
+// duration writes a time.Duration such as a token TTL as "2 часа" or
+// "15 minutes".
+func templateFuncs(locale string) map[string]any {
+	return map[string]any{
+		"duration": func(d time.Duration) string {
+			unit := time.Minute
+			if d >= time.Hour && d%time.Hour == 0 {
+				unit = time.Hour
+			}
+
+			n := max(int(d.Round(unit)/unit), 1)
+			return fmt.Sprintf("%d %s", n, durationUnits[locale][unit][pluralForm(locale, n)])
+		},
+	}
+}
+func pluralForm(locale string, n int) int {
+	if locale != "ru" {
+		if n == 1 {
+			return 0
+		}
+		return 2
+	}
+
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return 0
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"og-style/utils"
+	"sync"
+	"time"
+)
+
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts a function, such as pgxpool.Pool.Ping, to HealthChecker.
+type HealthCheckFunc func(ctx context.Context) error
+
+func (f HealthCheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type HealthCheck struct {
+	Name    string
+	Checker HealthChecker
+	Timeout time.Duration
+	// CacheTTL, when longer than the service's, reuses this dependency's last
+	// healthy status for that long, for checks that spend quota on the other
+	// side. A failed check is retried at the service's CacheTTL.
+	CacheTTL time.Duration
+}
+
+type DependencyStatus struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type ReadinessReport struct {
+	Ready        bool                        `json:"ready"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// HealthService runs Checks concurrently and reuses the report for CacheTTL,
+// so frequent probes don't turn into a ping per probe. Concurrent callers
+// wait for a single refresh.
+type HealthService struct {
+	Checks   []HealthCheck
+	CacheTTL time.Duration
+
+	mu        sync.Mutex
+	report    ReadinessReport
+	checkedAt time.Time
+}
+
+func (h *HealthService) Check() ReadinessReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.checkedAt.IsZero() && time.Since(h.checkedAt) < h.CacheTTL {
+		return h.report
+	}
+
+	report := ReadinessReport{Ready: true, Dependencies: make(map[string]DependencyStatus, len(h.Checks))}
+	mu, wg := sync.Mutex{}, sync.WaitGroup{}
+
+	for _, check := range h.Checks {
+		if status, ok := h.report.Dependencies[check.Name]; ok && status.Status == "up" && time.Since(status.CheckedAt) < check.CacheTTL {
+			report.Dependencies[check.Name] = status
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := runCheck(check)
+
+			mu.Lock()
+			report.Dependencies[check.Name] = status
+			if status.Status != "up" {
+				report.Ready = false
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	h.report, h.checkedAt = report, time.Now()
+	return report
+}
+func runCheck(check HealthCheck) DependencyStatus {
+	// The check is detached from the probe's request so a client hanging up
+	// does not get a cancellation cached as an outage.
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Checker.Check(ctx)
+
+	status := DependencyStatus{Status: "up", LatencyMS: time.Since(start).Milliseconds(), CheckedAt: start}
+	// The probe is unauthenticated, so the cause is only logged; the report
+	// carries a fixed code instead of driver or provider messages.
+	if err != nil {
+		utils.Logger(ctx).Error("health check failed", "check", check.Name, "error", err)
+		status.Status, status.Error = "down", "unreachable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			status.Error = "timeout"
+		}
+	}
+
+	return status
+}
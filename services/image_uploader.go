@@ -2,29 +2,61 @@ package services
 
 import (
 	"context"
+	"errors"
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"og-style/metrics"
+	"time"
 )
 
 type ImageUploaderService interface {
-	Upload(file any) (string, error)
+	Upload(ctx context.Context, file any) (string, error)
 }
 
 type CldImageUploaderService struct {
 	Cloudinary *cloudinary.Cloudinary
 }
 
-func (c *CldImageUploaderService) Upload(file any) (string, error) {
-	res, err := c.Cloudinary.Upload.Upload(context.Background(), file, uploader.UploadParams{
+func (c *CldImageUploaderService) Upload(ctx context.Context, file any) (string, error) {
+	ctx, span := otel.Tracer("og-style/services").Start(ctx, "cloudinary.upload", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	start := time.Now()
+	res, err := c.Cloudinary.Upload.Upload(ctx, file, uploader.UploadParams{
 		Folder:         "og-style",
 		UniqueFilename: api.Bool(true),
 		UseFilename:    api.Bool(true),
 	})
+	metrics.ImageUploadDuration.Observe(time.Since(start).Seconds())
+
+	if err == nil && res.Error.Message != "" {
+		err = errors.New(res.Error.Message)
+	}
 
 	if err != nil {
+		metrics.ImageUploadFailures.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return "", err
 	}
 
 	return res.SecureURL, nil
 }
+
+// Check pings the Cloudinary Admin API, which is rate limited per hour, so
+// give its health check a long CacheTTL.
+func (c *CldImageUploaderService) Check(ctx context.Context) error {
+	res, err := c.Cloudinary.Admin.Ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.Error.Message != "" {
+		return errors.New(res.Error.Message)
+	}
+	return nil
+}
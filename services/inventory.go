@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"og-style/db"
+	"og-style/utils"
+	"time"
+)
+
+type InventoryService interface {
+	Reserve(ctx context.Context, cartId, variantId, quantity int) error
+	Release(ctx context.Context, cartId, variantId int) error
+	ReleaseAll(ctx context.Context, cartId int) error
+}
+
+type PgInventoryService struct {
+	ReservationStorage db.ReservationStorage
+	TTL                time.Duration
+}
+
+func (i *PgInventoryService) Reserve(ctx context.Context, cartId, variantId, quantity int) error {
+	return i.ReservationStorage.Reserve(ctx, cartId, variantId, quantity, i.TTL)
+}
+func (i *PgInventoryService) Release(ctx context.Context, cartId, variantId int) error {
+	return i.ReservationStorage.Release(ctx, cartId, variantId)
+}
+func (i *PgInventoryService) ReleaseAll(ctx context.Context, cartId int) error {
+	return i.ReservationStorage.ReleaseAll(ctx, cartId)
+}
+
+// RunSweeper returns the stock of expired reservations every interval until ctx is done.
+func (i *PgInventoryService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if released, err := i.ReservationStorage.ReleaseExpired(ctx); err != nil {
+				utils.Logger(ctx).Error("release expired reservations", "error", err)
+			} else if released != 0 {
+				utils.Logger(ctx).Info("released expired reservations", "count", released)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"gopkg.in/gomail.v2"
+	"net"
+	"og-style/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Mailer interface {
+	HealthChecker
+	Send(mail models.Mail) error
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// ImplicitTLS dials with TLS from the start (usually port 465). Otherwise
+	// the connection is upgraded with STARTTLS when the server offers it.
+	ImplicitTLS        bool
+	InsecureSkipVerify bool
+}
+
+func (s *SMTPMailer) Send(mail models.Mail) error {
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+	d.SSL = s.ImplicitTLS
+	d.TLSConfig = &tls.Config{ServerName: s.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+
+	return d.DialAndSend(newMessage(s.From, mail))
+}
+
+// Check only verifies that the SMTP server accepts connections; logging in on
+// every probe would get the account rate limited.
+func (s *SMTPMailer) Check(ctx context.Context) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// FileMailer writes every mail as an RFC 822 file into a maildir at Dir, so
+// local mail can be read with any maildir-capable client.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (f *FileMailer) Send(mail models.Mail) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(f.Dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%d.%s.og-style", time.Now().UnixNano(), randomHex(4))
+	tmpPath := filepath.Join(f.Dir, "tmp", name)
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := newMessage(f.From, mail).WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(f.Dir, "new", name))
+}
+func (f *FileMailer) Check(ctx context.Context) error {
+	dir := filepath.Join(f.Dir, "tmp")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, ".health-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// MemoryMailer keeps sent mail in memory so tests can assert on it.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []models.Mail
+}
+
+func (m *MemoryMailer) Send(mail models.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, mail)
+	return nil
+}
+func (m *MemoryMailer) Check(ctx context.Context) error {
+	return nil
+}
+func (m *MemoryMailer) Sent() []models.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sent := make([]models.Mail, len(m.sent))
+	copy(sent, m.sent)
+	return sent
+}
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = nil
+}
+
+func newMessage(from string, mail models.Mail) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", mail.To...)
+	m.SetHeader("Subject", mail.Subject)
+
+	switch {
+	case mail.Text != "" && mail.HTML != "":
+		m.SetBody("text/plain", mail.Text)
+		m.AddAlternative("text/html", mail.HTML)
+	case mail.HTML != "":
+		m.SetBody("text/html", mail.HTML)
+	default:
+		m.SetBody("text/plain", mail.Text)
+	}
+
+	return m
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"og-style/db"
+	"og-style/metrics"
+	"og-style/models"
+	"og-style/utils"
+	"sync"
+	"time"
+)
+
+// outboxLease is how long a claimed batch stays reserved for this worker; it
+// must cover waiting for a free worker and the SMTP round trip.
+const outboxLease = time.Minute * 5
+
+// OutboxWorker drains the email outbox with a pool of Workers goroutines.
+// A failed email is retried after BaseBackoff * 2^(attempts-1), capped at
+// MaxBackoff, and is dead-lettered once it has been tried MaxAttempts times.
+type OutboxWorker struct {
+	Storage      db.OutboxStorage
+	Mailer       Mailer
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// Run blocks until ctx is done and all in-flight emails have been handled.
+func (o *OutboxWorker) Run(ctx context.Context) {
+	jobs := make(chan *models.OutboxEmail)
+	// Claimed emails are still delivered and marked after ctx is done.
+	deliverCtx := context.WithoutCancel(ctx)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < o.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				o.deliver(deliverCtx, email)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		emails, err := o.Storage.Claim(ctx, o.BatchSize, outboxLease)
+		if err != nil {
+			utils.Logger(ctx).Error("claim outbox emails", "error", err)
+		}
+
+		for _, email := range emails {
+			jobs <- email
+		}
+
+		if len(emails) == o.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+func (o *OutboxWorker) deliver(ctx context.Context, email *models.OutboxEmail) {
+	sendErr := o.Mailer.Send(email.Mail())
+	if sendErr == nil {
+		metrics.EmailsSent.WithLabelValues("sent").Inc()
+		if err := o.Storage.MarkSent(ctx, email.ID); err != nil {
+			utils.Logger(ctx).Error("mark outbox email as sent", "email_id", email.ID, "error", err)
+		}
+		return
+	}
+
+	dead := email.Attempts >= o.MaxAttempts
+	if dead {
+		metrics.EmailsSent.WithLabelValues("dead").Inc()
+		utils.Logger(ctx).Error("outbox email dead-lettered", "email_id", email.ID, "attempts", email.Attempts, "error", sendErr)
+	} else {
+		metrics.EmailsSent.WithLabelValues("retry").Inc()
+	}
+
+	if err := o.Storage.MarkFailed(ctx, email.ID, sendErr.Error(), time.Now().Add(o.backoff(email.Attempts)), dead); err != nil {
+		utils.Logger(ctx).Error("mark outbox email as failed", "email_id", email.ID, "error", err)
+	}
+}
+func (o *OutboxWorker) backoff(attempts int) time.Duration {
+	delay := o.BaseBackoff
+	for i := 1; i < attempts && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, o.MaxBackoff)
+}
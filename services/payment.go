@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"og-style/apperrors"
+	"og-style/models"
+	"sync"
+	"time"
+)
+
+const (
+	PaymentSignatureHeader = "X-Payment-Signature"
+
+	PaymentEventAuthorized = "payment.authorized"
+	PaymentEventSucceeded  = "payment.succeeded"
+	PaymentEventFailed     = "payment.failed"
+)
+
+var ErrInvalidSignature = apperrors.Unauthorized("invalid_signature", "неверная подпись вебхука")
+
+type PaymentProvider interface {
+	CreateIntent(orderId, amount int) (*models.PaymentIntent, error)
+	Capture(intentId string) error
+	// Refund returns amount of a captured intent. The gateway refunds once
+	// per idempotencyKey, so a retried call with the same key is a no-op.
+	Refund(intentId string, amount int, idempotencyKey string) error
+	VerifyWebhook(payload []byte, signature string) (*models.PaymentEvent, error)
+}
+
+// FakePaymentProvider is an in-process gateway for local development and tests.
+// Events are signed with Secret and, when WebhookURL is set, delivered to it.
+// With AutoConfirm every new intent is authorized right away, as if the
+// customer had paid.
+type FakePaymentProvider struct {
+	Secret      []byte
+	WebhookURL  string
+	AutoConfirm bool
+	Client      *http.Client
+
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	models.PaymentIntent
+	captured bool
+	refunded int
+	refunds  map[string]bool
+}
+
+func (f *FakePaymentProvider) CreateIntent(orderId, amount int) (*models.PaymentIntent, error) {
+	intent := fakeIntent{PaymentIntent: models.PaymentIntent{
+		ID:           "pi_" + randomHex(12),
+		ClientSecret: "secret_" + randomHex(16),
+		OrderID:      orderId,
+		Amount:       amount,
+	}}
+
+	f.mu.Lock()
+	if f.intents == nil {
+		f.intents = make(map[string]*fakeIntent)
+	}
+	f.intents[intent.ID] = &intent
+	f.mu.Unlock()
+
+	if f.AutoConfirm {
+		go f.emit(PaymentEventAuthorized, intent.PaymentIntent)
+	}
+
+	res := intent.PaymentIntent
+	return &res, nil
+}
+func (f *FakePaymentProvider) Capture(intentId string) error {
+	f.mu.Lock()
+	intent, ok := f.intents[intentId]
+	if !ok {
+		f.mu.Unlock()
+		return fmt.Errorf("payment intent %s not found", intentId)
+	}
+
+	alreadyCaptured := intent.captured
+	intent.captured = true
+	f.mu.Unlock()
+
+	if !alreadyCaptured {
+		go f.emit(PaymentEventSucceeded, intent.PaymentIntent)
+	}
+
+	return nil
+}
+func (f *FakePaymentProvider) Refund(intentId string, amount int, idempotencyKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentId]
+	if !ok {
+		return fmt.Errorf("payment intent %s not found", intentId)
+	}
+
+	if intent.refunds[idempotencyKey] {
+		return nil
+	}
+
+	if !intent.captured {
+		return fmt.Errorf("payment intent %s is not captured", intentId)
+	}
+
+	if intent.refunded+amount > intent.Amount {
+		return fmt.Errorf("refund exceeds captured amount of payment intent %s", intentId)
+	}
+
+	if intent.refunds == nil {
+		intent.refunds = make(map[string]bool)
+	}
+	intent.refunds[idempotencyKey] = true
+	intent.refunded += amount
+	return nil
+}
+func (f *FakePaymentProvider) VerifyWebhook(payload []byte, signature string) (*models.PaymentEvent, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, f.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event models.PaymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, apperrors.Validation("invalid_payload", "некорректное тело вебхука").Wrap(err)
+	}
+
+	return &event, nil
+}
+
+// SignEvent returns the payload and signature the gateway would send for event.
+func (f *FakePaymentProvider) SignEvent(event models.PaymentEvent) ([]byte, string, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, hex.EncodeToString(f.sign(payload)), nil
+}
+func (f *FakePaymentProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.Secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+func (f *FakePaymentProvider) emit(eventType string, intent models.PaymentIntent) {
+	if f.WebhookURL == "" {
+		return
+	}
+
+	payload, signature, err := f.SignEvent(models.PaymentEvent{
+		ID:       "evt_" + randomHex(12),
+		Type:     eventType,
+		IntentID: intent.ID,
+		OrderID:  intent.OrderID,
+		Amount:   intent.Amount,
+	})
+	if err != nil {
+		slog.Error("fake payment provider: emit webhook", "type", eventType, "error", err)
+		return
+	}
+
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 5}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, f.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		slog.Error("fake payment provider: emit webhook", "type", eventType, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(PaymentSignatureHeader, signature)
+
+	res, err := client.Do(req)
+	if err != nil {
+		slog.Error("fake payment provider: emit webhook", "type", eventType, "error", err)
+		return
+	}
+	res.Body.Close()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
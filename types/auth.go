@@ -1,9 +1,21 @@
 package types
 
-import "og-style/models"
+import (
+	"og-style/models"
+	"time"
+)
 
 type SignInResponse struct {
 	User         *models.User `json:"user"`
 	AccessToken  string       `json:"-"`
 	RefreshToken string       `json:"-"`
 }
+
+type Session struct {
+	ID         int       `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,33 @@
+package types
+
+type AddCartItem struct {
+	ProductID int    `json:"productId" validate:"required,min=1"`
+	Size      string `json:"size" validate:"required"`
+	Color     string `json:"color" validate:"required,hexcolor"`
+	Quantity  int    `json:"quantity" validate:"required,min=1,max=99"`
+}
+
+type UpdateCartItem struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=99"`
+}
+
+type CartItem struct {
+	ID              int      `json:"id" db:"id"`
+	ProductID       int      `json:"productId" db:"product_id"`
+	VariantID       int      `json:"variantId" db:"variant_id"`
+	Name            string   `json:"name" db:"name"`
+	Images          []string `json:"images" db:"images"`
+	Price           int      `json:"price" db:"price"`
+	DiscountedPrice *int     `json:"discountedPrice,omitempty" db:"discounted_price"`
+	Size            string   `json:"size" db:"size"`
+	Color           string   `json:"color" db:"color"`
+	Quantity        int      `json:"quantity" db:"quantity"`
+	Subtotal        int      `json:"subtotal" db:"-"`
+}
+
+type Cart struct {
+	ID            int         `json:"id"`
+	Items         []*CartItem `json:"items"`
+	TotalQuantity int         `json:"totalQuantity"`
+	Total         int         `json:"total"`
+}
@@ -0,0 +1,5 @@
+package types
+
+type UpdateOrderStatus struct {
+	Status string `json:"status" validate:"required,oneof=pending paid shipped delivered cancelled"`
+}
@@ -37,6 +37,7 @@ type GetProductsParams struct {
 	Page        int      `json:"page,omitempty" validate:"omitempty,min=1"`
 	Size        []string `json:"size,omitempty" validate:"omitempty,dive"`
 	Colors      []string `json:"colors,omitempty" validate:"omitempty,dive,hexcolor"`
+	InStock     bool     `json:"inStock,omitempty"`
 }
 
 type ProductFilters struct {
@@ -3,6 +3,7 @@ package types
 type CreateUser struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,gte=8"`
+	Locale   string `json:"locale" validate:"omitempty,oneof=ru en"`
 }
 
 type UpdateUser struct {
@@ -0,0 +1,13 @@
+package types
+
+type CreateVariant struct {
+	Size  string `json:"size" validate:"required"`
+	Color string `json:"color" validate:"required,hexcolor"`
+	SKU   string `json:"sku" validate:"required,lte=64"`
+	Stock int    `json:"stock" validate:"min=0"`
+}
+
+type UpdateVariant struct {
+	SKU   string `json:"sku" validate:"omitempty,lte=64"`
+	Stock *int   `json:"stock" validate:"omitempty,min=0"`
+}
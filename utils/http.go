@@ -1,14 +1,41 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/text/language"
+	"net"
 	"net/http"
+	"net/netip"
+	"og-style/apperrors"
+	"strings"
 )
 
-func SendError(w http.ResponseWriter, err error, statusCode int) {
+var localeMatcher = language.NewMatcher([]language.Tag{language.Russian, language.English})
+
+// StatusClientClosedRequest is the non-standard status logged when the client
+// disconnects before the response is written.
+const StatusClientClosedRequest = 499
+
+// SendError sends err with statusCode, unless err says otherwise: typed
+// errors from apperrors carry their own status and code, cancellations and
+// database outages get 499/503/504, and database errors are never shown to
+// the client.
+func SendError(w http.ResponseWriter, r *http.Request, err error, statusCode int) {
+	statusCode, code, message := errorResponse(err, statusCode)
+
+	if WantsProblem(r) {
+		sendProblem(w, newProblem(r, statusCode, code, message))
+		return
+	}
+
 	m := map[string]any{
 		"status":  "error",
-		"message": err.Error(),
+		"code":    code,
+		"message": message,
 	}
 
 	encoded, _ := json.Marshal(m)
@@ -18,10 +45,29 @@ func SendError(w http.ResponseWriter, err error, statusCode int) {
 	w.Write(encoded)
 }
 
-func SendValidatonErrors(w http.ResponseWriter, errors any) {
+// SendAppError sends an error returned by a processor. Anything that is not
+// an apperrors.Error is treated as internal and logged.
+func SendAppError(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperrors.From(err)
+	if _, _, _, unavailable := unavailableError(err); appErr.Kind == apperrors.KindInternal && !unavailable {
+		Logger(r.Context()).Error("request failed", "error", err)
+	}
+
+	SendError(w, r, appErr, http.StatusInternalServerError)
+}
+
+func SendValidatonErrors(w http.ResponseWriter, r *http.Request, errors ValidationErrors) {
+	if WantsProblem(r) {
+		problem := newProblem(r, http.StatusBadRequest, "validation_failed", validationDetails[Locale(r)])
+		problem.Errors = errors
+		sendProblem(w, problem)
+		return
+	}
+
 	m := map[string]any{
 		"status": "error",
-		"errors": errors,
+		"code":   "validation_failed",
+		"errors": errors.pairs(),
 	}
 
 	encoded, _ := json.Marshal(m)
@@ -30,17 +76,17 @@ func SendValidatonErrors(w http.ResponseWriter, errors any) {
 	w.WriteHeader(http.StatusBadRequest)
 	w.Write(encoded)
 }
-func BadRequestError(w http.ResponseWriter, err error) {
-	SendError(w, err, http.StatusBadRequest)
+func BadRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	SendError(w, r, err, http.StatusBadRequest)
 }
-func UnauthorizedError(w http.ResponseWriter, err error) {
-	SendError(w, err, http.StatusUnauthorized)
+func UnauthorizedError(w http.ResponseWriter, r *http.Request, err error) {
+	SendError(w, r, err, http.StatusUnauthorized)
 }
-func InternalServerError(w http.ResponseWriter, err error) {
-	SendError(w, err, http.StatusInternalServerError)
+func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	SendError(w, r, err, http.StatusInternalServerError)
 }
-func ForbiddenError(w http.ResponseWriter, err error) {
-	SendError(w, err, http.StatusForbidden)
+func ForbiddenError(w http.ResponseWriter, r *http.Request, err error) {
+	SendError(w, r, err, http.StatusForbidden)
 }
 
 func SendJSON(w http.ResponseWriter, data any, statusCode int) {
@@ -55,3 +101,138 @@ func SendJSON(w http.ResponseWriter, data any, statusCode int) {
 	w.WriteHeader(statusCode)
 	w.Write(encoded)
 }
+
+// TrustedProxies are the networks of the reverse proxies whose
+// X-Forwarded-For header ClientIP believes.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDRs such as "10.0.0.0/8" into TrustedProxies.
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+func (p TrustedProxies) contains(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the remote address of the connection. Only when that is a
+// trusted proxy is X-Forwarded-For consulted: it is walked from the right,
+// past the trusted hops, to the address the first of them saw the request
+// from. Anything left of that was written by the client and is ignored.
+func (p TrustedProxies) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !p.contains(addr) {
+		return remote
+	}
+
+	client := remote
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			break
+		}
+
+		client = addr.Unmap().String()
+		if !p.contains(addr) {
+			break
+		}
+	}
+	return client
+}
+
+// Locale picks the supported locale ("ru" or "en") that best matches the
+// Accept-Language header, defaulting to "ru".
+func Locale(r *http.Request) string {
+	tags, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	tag, _, _ := localeMatcher.Match(tags...)
+
+	if base, _ := tag.Base(); base.String() == "en" {
+		return "en"
+	}
+	return "ru"
+}
+
+// HTTPStatus is the status code that describes errors of kind.
+func HTTPStatus(kind apperrors.Kind) int {
+	switch kind {
+	case apperrors.KindNotFound:
+		return http.StatusNotFound
+	case apperrors.KindConflict:
+		return http.StatusConflict
+	case apperrors.KindValidation:
+		return http.StatusBadRequest
+	case apperrors.KindUnauthorized:
+		return http.StatusUnauthorized
+	case apperrors.KindForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+func errorResponse(err error, statusCode int) (int, string, string) {
+	if status, code, message, ok := unavailableError(err); ok {
+		return status, code, message
+	}
+
+	var appErr *apperrors.Error
+	if errors.As(err, &appErr) {
+		return HTTPStatus(appErr.Kind), appErr.Code, appErr.Message
+	}
+
+	if statusCode >= http.StatusInternalServerError || isDatabaseError(err) {
+		internal := apperrors.Internal(err)
+		return http.StatusInternalServerError, internal.Code, internal.Message
+	}
+
+	return statusCode, strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_"), err.Error()
+}
+
+// unavailableError maps errors that say nothing about the request itself —
+// a cancelled request, a query that ran out of time, an unreachable
+// database — to the status that describes them, whatever status the caller
+// picked.
+func unavailableError(err error) (int, string, string, bool) {
+	var connectErr *pgconn.ConnectError
+	var pgErr *pgconn.PgError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, "request_canceled", "запрос отменён", true
+	case errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err):
+		return http.StatusGatewayTimeout, "timeout", "сервер не успел обработать запрос, повторите его позже", true
+	case errors.As(err, &connectErr):
+		return http.StatusServiceUnavailable, "service_unavailable", "база данных временно недоступна", true
+	// too_many_connections, cannot_connect_now (the server is starting up or
+	// shutting down).
+	case errors.As(err, &pgErr) && (pgErr.Code == "53300" || pgErr.Code == "57P03"):
+		return http.StatusServiceUnavailable, "service_unavailable", "база данных временно недоступна", true
+	}
+
+	return 0, "", "", false
+}
+
+// isDatabaseError reports whether err came from pgx, whose messages describe
+// the schema and queries and must not reach clients.
+func isDatabaseError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, pgx.ErrTxClosed) || errors.Is(err, pgx.ErrTxCommitRollback)
+}
@@ -4,14 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"os"
 	"time"
 )
 
-func SignJWT(payload jwt.MapClaims) (string, error) {
+func SignJWT(payload jwt.MapClaims, secret string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	if tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET"))); err != nil {
+	if tokenString, err := token.SignedString([]byte(secret)); err != nil {
 		return "", err
 	} else {
 		return tokenString, err
@@ -19,13 +18,21 @@ func SignJWT(payload jwt.MapClaims) (string, error) {
 
 }
 
-func ParseJWT(tokenStr string) (jwt.MapClaims, error) {
+// Token types carried in the "typ" claim. Both kinds are signed with the same
+// secret, so ParseJWT checks the type to keep a refresh token from being used
+// as an access token and the other way round.
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+func ParseJWT(tokenStr, secret, typ string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return []byte(os.Getenv("JWT_SECRET")), nil
+		return []byte(secret), nil
 	})
 
 	if err != nil {
@@ -33,9 +40,13 @@ func ParseJWT(tokenStr string) (jwt.MapClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims), token.Valid; ok {
-		expiresDate, _ := time.Parse(time.RFC3339Nano, claims["expires"].(string))
+		if claims["typ"] != typ {
+			return nil, fmt.Errorf("expected a %s token", typ)
+		}
 
-		if time.Now().After(expiresDate) {
+		expires, _ := claims["expires"].(string)
+		expiresDate, err := time.Parse(time.RFC3339Nano, expires)
+		if err != nil || time.Now().After(expiresDate) {
 			return nil, errors.New("token expired")
 		}
 		return claims, nil
@@ -43,3 +54,10 @@ func ParseJWT(tokenStr string) (jwt.MapClaims, error) {
 		return nil, errors.New("something went wrong")
 	}
 }
+
+// ClaimInt returns a numeric claim as an int, reporting false when it is
+// missing or not a number.
+func ClaimInt(claims jwt.MapClaims, key string) (int, bool) {
+	value, ok := claims[key].(float64)
+	return int(value), ok
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// validationDetails is the problem detail of validation failures by locale.
+var validationDetails = map[string]string{
+	"ru": "запрос содержит некорректные поля",
+	"en": "the request contains invalid fields",
+}
+
+// Problem is an RFC 7807 problem details object. Code and Errors are
+// extension members: the stable error code and, for validation failures, the
+// invalid fields.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(r *http.Request, status int, code, detail string) *Problem {
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		// Not a registered status, so net/http has no text for it.
+		title = "Client Closed Request"
+	}
+
+	return &Problem{
+		Type:     "urn:og-style:error:" + code,
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		Code:     code,
+	}
+}
+
+// WantsProblem reports whether the client lists application/problem+json in
+// Accept. Other clients get the original {"status": "error", ...} bodies.
+func WantsProblem(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || mediaType != problemContentType {
+			continue
+		}
+
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
+func sendProblem(w http.ResponseWriter, problem *Problem) {
+	encoded, _ := json.Marshal(problem)
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(encoded)
+}
@@ -1,49 +1,157 @@
 package utils
 
 import (
-	"fmt"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	rutranslations "github.com/go-playground/validator/v10/translations/ru"
+	"reflect"
 	"strings"
 )
 
-func ValidateStruct(s any) *[][2]string {
-	validate := validator.New(validator.WithRequiredStructEnabled())
+// FieldError describes one invalid field: its path in the request body, the
+// validation rule it failed and a message for the user.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, e := range v {
+		messages = append(messages, e.Field+": "+e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// pairs is the [field, message] list sent to clients that did not ask for
+// problem details.
+func (v ValidationErrors) pairs() [][2]string {
+	pairs := make([][2]string, 0, len(v))
+	for _, e := range v {
+		field := e.Field[strings.LastIndex(e.Field, ".")+1:]
+		pairs = append(pairs, [2]string{field, e.Message})
+	}
+	return pairs
+}
+
+// validate is shared by every request: it caches what it learns about each
+// struct type, so building one per call would redo that work.
+var validate, translators = newValidator()
+
+// extraTranslations fills the gaps in (or replaces) the catalogs shipped with
+// the validator; "fallback" is used for any tag without a message of its own.
+var extraTranslations = map[string]map[string]string{
+	"en": {
+		"required_with": "{0} is required when {1} is set",
+		"fallback":      "{0} failed the {1} check",
+	},
+	"ru": {
+		"required_with": "{0} обязательное поле, если указано {1}",
+		"fallback":      "{0} не прошло проверку {1}",
+	},
+}
+
+func newValidator() (*validator.Validate, map[string]ut.Translator) {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(jsonName)
+
+	universal := ut.New(ru.New(), ru.New(), en.New())
+	translators := map[string]ut.Translator{}
+	for locale, register := range map[string]func(*validator.Validate, ut.Translator) error{
+		"ru": rutranslations.RegisterDefaultTranslations,
+		"en": entranslations.RegisterDefaultTranslations,
+	} {
+		trans, _ := universal.GetTranslator(locale)
+		if err := register(v, trans); err != nil {
+			panic(err)
+		}
+
+		for tag, text := range extraTranslations[locale] {
+			if tag == "fallback" {
+				if err := trans.Add(tag, text, false); err != nil {
+					panic(err)
+				}
+				continue
+			}
+
+			addText := func(trans ut.Translator) error { return trans.Add(tag, text, true) }
+			if err := v.RegisterTranslation(tag, trans, addText, translateWithParam); err != nil {
+				panic(err)
+			}
+		}
+		translators[locale] = trans
+	}
+
+	return v, translators
+}
+
+// ValidateStruct validates s and describes the invalid fields in locale ("ru"
+// or "en"; anything else falls back to "ru").
+func ValidateStruct(s any, locale string) ValidationErrors {
 	err := validate.Struct(s)
 	if err == nil {
 		return nil
 	}
 
-	errors := [][2]string{}
+	trans, ok := translators[locale]
+	if !ok {
+		trans = translators["ru"]
+	}
+
+	errors := ValidationErrors{}
 	for _, err := range err.(validator.ValidationErrors) {
-		fmt.Println(err.Field(), err.Tag(), err.Param())
-		str := generateErrMessage(err.Field(), err.Tag(), err.Param())
-		errors = append(errors, [2]string{strings.ToLower(err.Field()), str})
-
-	}
-	return &errors
-}
-
-func generateErrMessage(field, tag, param string) string {
-	switch tag {
-	case "email":
-		return "Не корректный эл. адрес"
-	case "gte":
-		return fmt.Sprintf("должно быть более %s символов", param)
-	case "lte":
-		return fmt.Sprintf("должно быть меньше %s символов", param)
-	case "required":
-		return "это поле является обязательным"
-	case "oneof":
-		return fmt.Sprintf("должно быть один из вариантов %s", strings.Join(strings.Split(param, " "), ","))
-	case "min":
-		return fmt.Sprintf("Минимальное значение %s", param)
-	case "max":
-		return fmt.Sprintf("Максимальное значение %s", param)
-	case "required_with":
-		return fmt.Sprintf("Это поле обязательно для заполнения после выбора значения в поле %s", param)
-	case "len":
-		return fmt.Sprintf("количество элементов должно быть %s", param)
-	default:
-		return ""
+		errors = append(errors, FieldError{
+			Field:   fieldPath(err.Namespace()),
+			Code:    err.Tag(),
+			Message: translate(err, trans),
+		})
+	}
+	return errors
+}
+func translate(e validator.FieldError, trans ut.Translator) string {
+	// Translate falls back to the raw validator message when the tag has no
+	// translation.
+	if message := e.Translate(trans); message != e.Error() {
+		return message
+	}
+
+	message, _ := trans.T("fallback", e.Field(), e.Tag())
+	return message
+}
+
+// translateWithParam fills {1} with the tag's parameter, which for
+// required_with is a Go field name, shown lowercased like the JSON name.
+func translateWithParam(trans ut.Translator, e validator.FieldError) string {
+	param := e.Param()
+	if e.Tag() == "required_with" && param != "" {
+		param = strings.ToLower(param[:1]) + param[1:]
+	}
+
+	message, _ := trans.T(e.Tag(), e.Field(), param)
+	return message
+}
+
+// fieldPath turns a validator namespace such as "AddCartItem.items[0].size"
+// into "items[0].size".
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// jsonName names fields as they appear in request bodies. Fields hidden from
+// JSON keep their Go name with a lowercase first letter.
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return strings.ToLower(field.Name[:1]) + field.Name[1:]
 	}
+	return name
 }